@@ -19,8 +19,10 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	openfunction "github.com/openfunction/apis/core/v1beta1"
@@ -29,16 +31,29 @@ import (
 	revisioncontroller "github.com/openfunction/revision-controller/pkg/revision-controller"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/image"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
 	"github.com/openfunction/revision-controller/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	revisionControllerKey       = "openfunction.io/revision-controller"
 	revisionControllerParamsKey = "openfunction.io/revision-controller-params"
+
+	credentialsIndexKey = "spec.credentials"
+	// serviceAccountIndexPrefix prefixes the service accounts in the credentials index.
+	serviceAccountIndexPrefix = "serviceaccount/"
 )
 
 var (
@@ -48,15 +63,25 @@ var (
 // FunctionReconciler reconciles a Function object
 type FunctionReconciler struct {
 	client.Client
-	log logr.Logger
-
+	// clientset is used to request the tokens of the service accounts.
+	clientset kubernetes.Interface
+	recorder  record.EventRecorder
+	log       logr.Logger
+	// defaultParams are the cluster defaults of the params, they are overridden by the params of the function.
+	defaultParams map[string]string
+
+	// The revision controllers are also listed by the webhook receivers.
+	lock                sync.RWMutex
 	revisionControllers map[string]revisioncontroller.RevisionController
 }
 
-func NewFunctionReconciler(mgr manager.Manager) *FunctionReconciler {
+func NewFunctionReconciler(mgr manager.Manager, defaultParams map[string]string) *FunctionReconciler {
 	r := &FunctionReconciler{
 		Client:              mgr.GetClient(),
+		clientset:           kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+		recorder:            mgr.GetEventRecorderFor("revision-controller"),
 		log:                 ctrl.Log.WithName("controllers").WithName("Function"),
+		defaultParams:       defaultParams,
 		revisionControllers: make(map[string]revisioncontroller.RevisionController),
 	}
 
@@ -65,6 +90,10 @@ func NewFunctionReconciler(mgr manager.Manager) *FunctionReconciler {
 
 //+kubebuilder:rbac:groups=core.openfunction.io,resources=functions,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.openfunction.io,resources=functions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -96,21 +125,62 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if fn.Annotations == nil ||
 		fn.Annotations[revisionControllerKey] != "enable" {
 		r.cleanRevisionControllerByFunction(fn)
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, git.DeletePreviews(r.Client, fn)
+	}
+
+	// The config errors are reported to the user by the events of the function.
+	if err := r.addRevisionController(fn); err != nil {
+		r.recorder.Event(fn, corev1.EventTypeWarning, "RevisionControllerError", err.Error())
+		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, r.addRevisionController(fn)
+	return ctrl.Result{}, nil
 }
 
 func (r *FunctionReconciler) addRevisionController(fn *openfunction.Function) error {
-	config, err := getRevisionControllerConfig(fn.Annotations[revisionControllerParamsKey])
+	config, err := getRevisionControllerConfig(fn.Annotations[revisionControllerParamsKey], r.defaultParams)
 	if err != nil {
 		return err
 	}
 
-	revisionControllerType := config[constants.RevisionControllerType]
-	r.cleanRevisionControllerByFunction(fn, revisionControllerType)
+	revisionControllerTypes := utils.SplitList(config[constants.RevisionControllerType])
+	r.cleanRevisionControllerByFunction(fn, revisionControllerTypes...)
+
+	// The previews are synced by the source revision controller only.
+	if !utils.StringInList(constants.RevisionControllerTypeSource, revisionControllerTypes) {
+		if err := git.DeletePreviews(r.Client, fn); err != nil {
+			return err
+		}
+	}
+
+	// The error of a type doesn't stop the revision controllers of the other types.
+	var errs []error
+	for _, revisionControllerType := range revisionControllerTypes {
+		typeConfig := make(map[string]string)
+		for k, v := range config {
+			typeConfig[k] = v
+		}
+		typeConfig[constants.RevisionControllerType] = revisionControllerType
+
+		// The image built by the source revision controllers is deployed by the build,
+		// so the image revision controller waits for the build instead of restarting the serving again.
+		if revisionControllerType == constants.RevisionControllerTypeImage && typeConfig[constants.WaitForBuild] == "" &&
+			(utils.StringInList(constants.RevisionControllerTypeSource, revisionControllerTypes) ||
+				utils.StringInList(constants.RevisionControllerTypeSourceImage, revisionControllerTypes)) {
+			typeConfig[constants.WaitForBuild] = "true"
+		}
+
+		if err := r.addRevisionControllerOfType(fn, revisionControllerType, typeConfig); err != nil {
+			errs = append(errs, fmt.Errorf("%s revision controller error, %s", revisionControllerType, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
 
+func (r *FunctionReconciler) addRevisionControllerOfType(fn *openfunction.Function, revisionControllerType string, config map[string]string) error {
+	// The additional sources are watched even if the default source is not.
+	watchDefaultSource := true
 	switch revisionControllerType {
 	case constants.RevisionControllerTypeSource:
 		if fn.Spec.Build == nil {
@@ -120,15 +190,15 @@ func (r *FunctionReconciler) addRevisionController(fn *openfunction.Function) er
 
 		if fn.Spec.Build.SrcRepo.Url == "" {
 			r.log.V(1).Info("git url must be set for source revision controller")
-			r.deleteRevisionController(fn, revisionControllerType)
-			return nil
+			watchDefaultSource = false
 		}
 
-		if fn.Spec.Build.SrcRepo.Revision != nil {
+		// The source revision is pinned to a commit by the rollback, keep watching the original revision.
+		rollback, _ := rollout.GetRollback(fn, constants.RevisionControllerTypeSource)
+		if fn.Spec.Build.SrcRepo.Revision != nil && rollback == nil {
 			if commitShaRegEx.MatchString(*fn.Spec.Build.SrcRepo.Revision) {
 				r.log.V(1).Info("source code point to a commit, no need to start revision controller")
-				r.deleteRevisionController(fn, revisionControllerType)
-				return nil
+				watchDefaultSource = false
 			}
 		}
 	case constants.RevisionControllerTypeSourceImage:
@@ -151,13 +221,72 @@ func (r *FunctionReconciler) addRevisionController(fn *openfunction.Function) er
 		return fmt.Errorf("unspport revision controller type, %s", revisionControllerType)
 	}
 
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
 	key := strings.Join([]string{fn.Namespace, fn.Name, revisionControllerType}, "/")
+	if revisionControllerType != constants.RevisionControllerTypeSource {
+		return r.ensureRevisionController(fn, key, revisionControllerType, config)
+	}
+
+	sources, err := git.GetSources(config)
+	if err != nil {
+		return err
+	}
+
+	// Stop the revision controllers of the removed sources.
+	names := make(map[string]bool)
+	for _, source := range sources {
+		names[key+"/"+source.Name] = true
+	}
+	for k := range r.revisionControllers {
+		if strings.HasPrefix(k, key+"/") && !names[k] {
+			r.stopRevisionController(k)
+		}
+	}
+
+	if watchDefaultSource {
+		if err := r.ensureRevisionController(fn, key, revisionControllerType, config); err != nil {
+			return err
+		}
+	} else {
+		r.stopRevisionController(key)
+	}
+
+	for _, source := range sources {
+		sourceConfig := make(map[string]string)
+		for k, v := range config {
+			sourceConfig[k] = v
+		}
+		sourceConfig[constants.SourceName] = source.Name
+
+		if err := r.ensureRevisionController(fn, key+"/"+source.Name, revisionControllerType, sourceConfig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureRevisionController updates the revision controller of the key, or starts it if it's not running,
+// the lock must be held by the caller.
+func (r *FunctionReconciler) ensureRevisionController(fn *openfunction.Function, key string, revisionControllerType string, config map[string]string) error {
 	rc := r.revisionControllers[key]
 	if rc != nil {
-		return rc.Update(config)
+		if err := rc.Update(config); err != nil {
+			return err
+		}
+
+		// Apply the approved revision immediately instead of waiting for the next polling,
+		// the revisions are keyed by the key of the revision controller without the function.
+		if rollout.IsApproved(fn, strings.TrimPrefix(key, fn.Namespace+"/"+fn.Name+"/")) {
+			rc.Trigger()
+		}
+
+		return nil
 	}
 
-	rc, err = newRevisionController(r.Client, fn, revisionControllerType, config)
+	rc, err := newRevisionController(r.Client, r.clientset, r.recorder, fn, revisionControllerType, config)
 	if err != nil {
 		return err
 	}
@@ -181,19 +310,56 @@ func (r *FunctionReconciler) cleanRevisionControllerByFunction(fn *openfunction.
 }
 
 func (r *FunctionReconciler) deleteRevisionController(fn *openfunction.Function, revisionControllerType string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
 	key := strings.Join([]string{fn.Namespace, fn.Name, revisionControllerType}, "/")
+	for k := range r.revisionControllers {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			r.stopRevisionController(k)
+		}
+	}
+}
+
+// stopRevisionController stops the revision controller of the key, the lock must be held by the caller.
+func (r *FunctionReconciler) stopRevisionController(key string) {
 	if rc, ok := r.revisionControllers[key]; ok {
 		rc.Stop()
 		delete(r.revisionControllers, key)
 	}
 }
 
-func getRevisionControllerConfig(params string) (map[string]string, error) {
-	config := make(map[string]string)
-	if err := utils.YamlUnmarshal([]byte(params), config); err != nil {
+// RevisionControllers returns the running revision controllers.
+func (r *FunctionReconciler) RevisionControllers() []revisioncontroller.RevisionController {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var rcs []revisioncontroller.RevisionController
+	for _, rc := range r.revisionControllers {
+		rcs = append(rcs, rc)
+	}
+
+	return rcs
+}
+
+func getRevisionControllerConfig(params string, defaultParams map[string]string) (map[string]string, error) {
+	functionParams, err := utils.YamlUnmarshalParams([]byte(params))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRestrictedParams(functionParams, defaultParams); err != nil {
 		return nil, err
 	}
 
+	config := make(map[string]string)
+	for k, v := range defaultParams {
+		config[k] = v
+	}
+	for k, v := range functionParams {
+		config[k] = v
+	}
+
 	if config[constants.RevisionControllerType] == "" {
 		config[constants.RevisionControllerType] = constants.RevisionControllerTypeSource
 	}
@@ -201,20 +367,177 @@ func getRevisionControllerConfig(params string) (map[string]string, error) {
 	return config, nil
 }
 
-func newRevisionController(c client.Client, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
+// checkRestrictedParams checks the params which make the controller read the files in its pod or send
+// the credentials to an url, the function can set them only to the values of the default params or to
+// the values allowed by the `allowed-paths` and `allowed-token-exchange-urls` of the default params.
+func checkRestrictedParams(functionParams map[string]string, defaultParams map[string]string) error {
+	for _, key := range []string{constants.AllowedPaths, constants.AllowedTokenExchangeURLs} {
+		if _, ok := functionParams[key]; ok {
+			return fmt.Errorf("%s can only be set in the default params", key)
+		}
+	}
+
+	for _, key := range []string{constants.CredentialPath, constants.CertsDir} {
+		value, ok := functionParams[key]
+		if !ok || value == defaultParams[key] {
+			continue
+		}
+
+		allowed := false
+		path := filepath.Clean(value)
+		for _, item := range utils.SplitList(defaultParams[constants.AllowedPaths]) {
+			item = filepath.Clean(item)
+			if path == item || strings.HasPrefix(path, item+string(filepath.Separator)) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("%s %s is not allowed, the allowed paths are set by %s of the default params", key, value, constants.AllowedPaths)
+		}
+	}
+
+	if value, ok := functionParams[constants.TokenExchangeURL]; ok && value != defaultParams[constants.TokenExchangeURL] &&
+		!utils.StringInList(value, utils.SplitList(defaultParams[constants.AllowedTokenExchangeURLs])) {
+		return fmt.Errorf("%s %s is not allowed, the allowed urls are set by %s of the default params",
+			constants.TokenExchangeURL, value, constants.AllowedTokenExchangeURLs)
+	}
+
+	return nil
+}
+
+func newRevisionController(c client.Client, clientset kubernetes.Interface, recorder record.EventRecorder, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
 	switch revisionControllerType {
 	case constants.RevisionControllerTypeSource:
-		return git.NewRevisionController(c, fn, revisionControllerType, config)
+		return git.NewRevisionController(c, clientset, recorder, fn, revisionControllerType, config)
 	case constants.RevisionControllerTypeSourceImage, constants.RevisionControllerTypeImage:
-		return image.NewRevisionController(c, fn, revisionControllerType, config)
+		return image.NewRevisionController(c, clientset, recorder, fn, revisionControllerType, config)
 	default:
 		return nil, fmt.Errorf("unspported revision controller type, %s", revisionControllerType)
 	}
 }
 
+// findFunctionsForSecret returns the functions which reference the secret as the source credential
+// or the image credential, directly or by the image pull secrets of the service account, so that
+// the revision controllers can reload the rotated credential.
+func (r *FunctionReconciler) findFunctionsForSecret(obj client.Object) []reconcile.Request {
+	requests := r.findFunctionsByIndex(obj.GetNamespace(), obj.GetName())
+
+	sas := &corev1.ServiceAccountList{}
+	if err := r.List(context.Background(), sas, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.log.Error(err, "list service accounts for secret error", "Secret", obj.GetNamespace()+"/"+obj.GetName())
+		return requests
+	}
+
+	for _, sa := range sas.Items {
+		for _, ref := range sa.ImagePullSecrets {
+			if ref.Name == obj.GetName() {
+				requests = append(requests, r.findFunctionsForServiceAccount(&sa)...)
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// findFunctionsForServiceAccount returns the functions which use the image pull secrets of the service account.
+func (r *FunctionReconciler) findFunctionsForServiceAccount(obj client.Object) []reconcile.Request {
+	return r.findFunctionsByIndex(obj.GetNamespace(), serviceAccountIndexPrefix+obj.GetName())
+}
+
+func (r *FunctionReconciler) findFunctionsByIndex(namespace string, value string) []reconcile.Request {
+	fns := &openfunction.FunctionList{}
+	if err := r.List(context.Background(), fns,
+		client.InNamespace(namespace),
+		client.MatchingFields{credentialsIndexKey: value}); err != nil {
+		r.log.Error(err, "list functions for credential error", "Credential", namespace+"/"+value)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, fn := range fns.Items {
+		if fn.Annotations == nil || fn.Annotations[revisionControllerKey] != "enable" {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      fn.Name,
+				Namespace: fn.Namespace,
+			},
+		})
+	}
+
+	return requests
+}
+
+// getFunctionCredentials returns the secrets used by the function, including those set by the default params,
+// and the service accounts whose image pull secrets are used, which are prefixed with `serviceaccount/`.
+func (r *FunctionReconciler) getFunctionCredentials(obj client.Object) []string {
+	fn := obj.(*openfunction.Function)
+	var credentials []string
+	if fn.Spec.Build != nil && fn.Spec.Build.SrcRepo != nil &&
+		fn.Spec.Build.SrcRepo.Credentials != nil && fn.Spec.Build.SrcRepo.Credentials.Name != "" {
+		credentials = append(credentials, fn.Spec.Build.SrcRepo.Credentials.Name)
+	}
+
+	if fn.Spec.ImageCredentials != nil && fn.Spec.ImageCredentials.Name != "" &&
+		!utils.StringInList(fn.Spec.ImageCredentials.Name, credentials) {
+		credentials = append(credentials, fn.Spec.ImageCredentials.Name)
+	}
+
+	if fn.Annotations != nil {
+		if config, err := getRevisionControllerConfig(fn.Annotations[revisionControllerParamsKey], r.defaultParams); err == nil {
+			names := utils.SplitList(config[constants.ImagePullSecrets])
+			for _, key := range []string{constants.CosignKeySecret, constants.TLSSecret} {
+				if config[key] != "" {
+					names = append(names, config[key])
+				}
+			}
+
+			sources, _ := git.GetSources(config)
+			for _, source := range sources {
+				if source.Credentials != "" {
+					names = append(names, source.Credentials)
+				}
+			}
+
+			for _, revisionControllerType := range utils.SplitList(config[constants.RevisionControllerType]) {
+				if revisionControllerType == constants.RevisionControllerTypeSource {
+					continue
+				}
+
+				typeConfig := make(map[string]string)
+				for k, v := range config {
+					typeConfig[k] = v
+				}
+				typeConfig[constants.RevisionControllerType] = revisionControllerType
+				names = append(names, serviceAccountIndexPrefix+image.ServiceAccountName(fn, typeConfig))
+			}
+
+			for _, name := range names {
+				if !utils.StringInList(name, credentials) {
+					credentials = append(credentials, name)
+				}
+			}
+		}
+	}
+
+	return credentials
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *FunctionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &openfunction.Function{},
+		credentialsIndexKey, r.getFunctionCredentials); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&openfunction.Function{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findFunctionsForSecret)).
+		Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.EnqueueRequestsFromMapFunc(r.findFunctionsForServiceAccount)).
 		Complete(r)
 }
@@ -32,6 +32,8 @@ import (
 
 	corev1beta1 "github.com/openfunction/apis/core/v1beta1"
 	"github.com/openfunction/revision-controller/controllers"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	"github.com/openfunction/revision-controller/pkg/webhook"
 )
 
 var (
@@ -51,6 +53,9 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var interval time.Duration
+	var defaultParamsFile string
+	var webhookAddr string
+	var webhookToken string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -58,6 +63,14 @@ func main() {
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.DurationVar(&interval, "builder-check-interval", time.Minute, "The interval used to check the expired builder")
+	flag.StringVar(&defaultParamsFile, "default-params-file", "",
+		"The yaml file of the default revision controller params, such as the registry mirrors and the proxy, "+
+			"the params of the function take precedence.")
+	flag.StringVar(&webhookAddr, "webhook-bind-address", "",
+		"The address the registry webhook receiver binds to, the receiver is disabled if it's empty.")
+	flag.StringVar(&webhookToken, "webhook-token", os.Getenv("WEBHOOK_TOKEN"),
+		"The token used to authenticate the registry webhooks, it's required if the receiver is enabled, "+
+			"it can also be set by the env WEBHOOK_TOKEN.")
 
 	// Use `--zap-log-level=debug` to enable debug log.
 	opts := zap.Options{
@@ -69,6 +82,21 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var defaultParams map[string]string
+	if defaultParamsFile != "" {
+		data, err := os.ReadFile(defaultParamsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read default params file")
+			os.Exit(1)
+		}
+
+		defaultParams, err = utils.YamlUnmarshalParams(data)
+		if err != nil {
+			setupLog.Error(err, "unable to parse default params file")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -82,11 +110,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controllers.NewFunctionReconciler(mgr).SetupWithManager(mgr); err != nil {
+	reconciler := controllers.NewFunctionReconciler(mgr, defaultParams)
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create function controller")
 		os.Exit(1)
 	}
 
+	if webhookAddr != "" {
+		if webhookToken == "" {
+			setupLog.Error(nil, "webhook token must be set when the registry webhook receiver is enabled")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(webhook.NewRegistryReceiver(webhookAddr, webhookToken, reconciler)); err != nil {
+			setupLog.Error(err, "unable to set up registry webhook receiver")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
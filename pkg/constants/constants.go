@@ -10,10 +10,85 @@ const (
 	AuthType               = "auth-type"
 	Project                = "project-id"
 	InsecureRegistry       = "insecure-registry"
+	UsernameKey            = "username-key"
+	PasswordKey            = "password-key"
+	CredentialSource       = "credential-source"
+	CredentialPath         = "credential-path"
+	TokenExchangeURL       = "token-exchange-url"
+	TokenAudience          = "token-audience"
+	Anonymous              = "anonymous"
+	ImagePullSecrets       = "image-pull-secrets"
+	ServiceAccount         = "service-account"
+	CloudKeychains         = "cloud-keychains"
+	TagPattern             = "tag-pattern"
+	SemverRange            = "semver-range"
+	TagOrder               = "tag-order"
+	Platform               = "platform"
+	CosignKeySecret        = "cosign-key-secret"
+	CosignKey              = "cosign-key"
+	VerifyAttestation      = "verify-attestation"
+	AttestationPredicate   = "attestation-predicate-type"
+	PinDigest              = "pin-digest"
+	RegistryMirrors        = "registry-mirrors"
+	CertsDir               = "certs-dir"
+	TLSSecret              = "tls-secret"
+	HTTPProxy              = "http-proxy"
+	HTTPSProxy             = "https-proxy"
+	NoProxy                = "no-proxy"
+	Approval               = "approval"
+	Schedule               = "schedule"
+	ScheduleWindow         = "schedule-window"
+	BlackoutWindows        = "blackout-windows"
+	Timezone               = "timezone"
+	QuietPeriod            = "quiet-period"
+	InitialRevision        = "initial-revision"
+	HistoryLimit           = "history-limit"
+	Rollback               = "rollback"
+	RollbackCooldown       = "rollback-cooldown"
+	Paused                 = "paused"
+	PinRevision            = "pin-revision"
+	Sources                = "sources"
+	SourceName             = "source-name"
+	WaitForBuild           = "wait-for-build"
+	Preview                = "preview"
+	PreviewForks           = "preview-forks"
+	CommitStatus           = "commit-status"
+	CommitStatusContext    = "commit-status-context"
+	FollowDefaultBranch    = "follow-default-branch"
+
+	// The params only allowed in the default params.
+	AllowedPaths             = "allowed-paths"
+	AllowedTokenExchangeURLs = "allowed-token-exchange-urls"
 
 	RevisionControllerTypeSource      = "source"
 	RevisionControllerTypeSourceImage = "source-image"
 	RevisionControllerTypeImage       = "image"
 
+	CredentialSourceSecret        = "secret"
+	CredentialSourceFile          = "file"
+	CredentialSourceTokenExchange = "token-exchange"
+
+	ApprovalManual = "manual"
+
+	InitialRevisionBaseline = "baseline"
+	InitialRevisionWait     = "wait"
+	InitialRevisionTrigger  = "trigger"
+
+	RollbackAuto = "auto"
+
 	DefaultPollingInterval = time.Second * 5
+	DefaultSourceName      = "default"
+	DefaultStatusContext   = "openfunction/revision-controller"
+)
+
+const (
+	ImageDigestsAnnotation     = "openfunction.io/revision-controller-image-digests"
+	PendingRevisionAnnotation  = "openfunction.io/revision-controller-pending-revision"
+	ApprovedRevisionAnnotation = "openfunction.io/revision-controller-approved-revision"
+	RevisionHistoryAnnotation  = "openfunction.io/revision-controller-history"
+	RollbackAnnotation         = "openfunction.io/revision-controller-rollback"
+	ConditionsAnnotation       = "openfunction.io/revision-controller-conditions"
+
+	PreviewOfLabel          = "openfunction.io/revision-controller-preview-of"
+	PreviewPullRequestLabel = "openfunction.io/revision-controller-preview-pull-request"
 )
@@ -0,0 +1,114 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultServiceAccount = "default"
+)
+
+// ErrNotSpecified is returned when the secret credential source is used but no secret is referenced.
+var ErrNotSpecified = errors.New("credential not specified")
+
+// Credential holds the credential data, the data is keyed in the same way as the data of a Kubernetes Secret.
+type Credential struct {
+	Name string
+	Type v1.SecretType
+	Data map[string][]byte
+	// Expiry is the time the credential expires, zero means the credential never expires.
+	Expiry time.Time
+	// Version is the resource version of the secret the credential is read from.
+	Version string
+
+	// source identifies the source the credential is got from.
+	source string
+}
+
+// Expired reports whether the credential is expired or will be expired soon.
+func (c *Credential) Expired() bool {
+	if c == nil || c.Expiry.IsZero() {
+		return false
+	}
+
+	return time.Now().Add(time.Minute).After(c.Expiry)
+}
+
+// Source provides the credential used to access git providers and image registries.
+type Source interface {
+	Get(ctx context.Context) (*Credential, error)
+	// Changed reports whether the credential got from the source before must be got again.
+	Changed(ctx context.Context, credential *Credential) (bool, error)
+}
+
+// Refresh returns the cached credential if it's got from the source and is not changed, otherwise
+// the credential is got from the source again, so that the token is not exchanged on every reconcile.
+func Refresh(ctx context.Context, source Source, cached *Credential) (*Credential, error) {
+	if cached != nil {
+		changed, err := source.Changed(ctx, cached)
+		if err != nil {
+			return nil, err
+		}
+
+		if !changed {
+			return cached, nil
+		}
+	}
+
+	return source.Get(ctx)
+}
+
+// NewSource returns the credential source specified by the `credential-source` param,
+// the secret referenced by the function is used by default.
+func NewSource(c client.Client, clientset kubernetes.Interface, namespace string, ref *v1.LocalObjectReference, config map[string]string) (Source, error) {
+	switch config[constants.CredentialSource] {
+	case "", constants.CredentialSourceSecret:
+		if ref == nil || ref.Name == "" {
+			return nil, ErrNotSpecified
+		}
+
+		return &secretSource{
+			Client:    c,
+			name:      ref.Name,
+			namespace: namespace,
+		}, nil
+	case constants.CredentialSourceFile:
+		if config[constants.CredentialPath] == "" {
+			return nil, fmt.Errorf("%s must be specified", constants.CredentialPath)
+		}
+
+		return &fileSource{path: config[constants.CredentialPath]}, nil
+	case constants.CredentialSourceTokenExchange:
+		if config[constants.TokenExchangeURL] == "" {
+			return nil, fmt.Errorf("%s must be specified", constants.TokenExchangeURL)
+		}
+
+		serviceAccount := config[constants.ServiceAccount]
+		if serviceAccount == "" {
+			serviceAccount = defaultServiceAccount
+		}
+
+		audience := config[constants.TokenAudience]
+		if audience == "" {
+			audience = config[constants.TokenExchangeURL]
+		}
+
+		return &tokenExchangeSource{
+			clientset:      clientset,
+			namespace:      namespace,
+			serviceAccount: serviceAccount,
+			url:            config[constants.TokenExchangeURL],
+			audience:       audience,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unspport credential source, %s", config[constants.CredentialSource])
+	}
+}
@@ -0,0 +1,111 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	tokenKey = "token"
+
+	// subjectTokenExpiration is the lifetime of the service account token exchanged, it's the minimum allowed.
+	subjectTokenExpiration = 10 * time.Minute
+
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// tokenExchangeSource exchanges a token of the service account of the function for an access token
+// with an OAuth 2.0 token exchange (RFC 8693) endpoint. The service account token is minted with
+// the TokenRequest api for the audience, so that the token of the controller is never sent out.
+type tokenExchangeSource struct {
+	clientset      kubernetes.Interface
+	namespace      string
+	serviceAccount string
+	url            string
+	audience       string
+}
+
+type tokenExchangeResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (s *tokenExchangeSource) Get(ctx context.Context) (*Credential, error) {
+	expiration := int64(subjectTokenExpiration.Seconds())
+	tokenRequest, err := s.clientset.CoreV1().ServiceAccounts(s.namespace).CreateToken(ctx, s.serviceAccount,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         []string{s.audience},
+				ExpirationSeconds: &expiration,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", grantTypeTokenExchange)
+	form.Set("subject_token", tokenRequest.Status.Token)
+	form.Set("subject_token_type", tokenTypeJWT)
+	form.Set("audience", s.audience)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange token error, %s", resp.Status)
+	}
+
+	res := &tokenExchangeResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+
+	if res.AccessToken == "" {
+		return nil, fmt.Errorf("%s", "exchange token error, no access token returned")
+	}
+
+	credential := &Credential{
+		Name: s.url,
+		Data: map[string][]byte{
+			tokenKey: []byte(res.AccessToken),
+		},
+		source: s.id(),
+	}
+	if res.ExpiresIn > 0 {
+		credential.Expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return credential, nil
+}
+
+// Changed reports whether the token must be exchanged again, it's exchanged again when the access token expires.
+func (s *tokenExchangeSource) Changed(_ context.Context, credential *Credential) (bool, error) {
+	return credential.source != s.id() || credential.Expired(), nil
+}
+
+func (s *tokenExchangeSource) id() string {
+	return strings.Join([]string{"token-exchange", s.namespace, s.serviceAccount, s.url, s.audience}, "/")
+}
@@ -0,0 +1,77 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// fileRefreshInterval is the interval the credential files are read again, so that the rotated credential is used.
+	fileRefreshInterval = 2 * time.Minute
+)
+
+// fileSource reads the credential from a directory, each file is a key of the credential.
+// The directory is usually a mounted secret volume or is written by a sidecar such as the Vault agent.
+type fileSource struct {
+	path string
+}
+
+func (s *fileSource) Get(_ context.Context) (*Credential, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &Credential{
+		Name:   s.path,
+		Type:   v1.SecretTypeOpaque,
+		Data:   make(map[string][]byte),
+		Expiry: time.Now().Add(fileRefreshInterval),
+		source: s.id(),
+	}
+	for _, entry := range entries {
+		// Skip the `..data` like entries created by the atomic writer of Kubernetes volumes.
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+
+		file := filepath.Join(s.path, entry.Name())
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Data[entry.Name()] = data
+	}
+
+	if _, ok := credential.Data[v1.DockerConfigJsonKey]; ok {
+		credential.Type = v1.SecretTypeDockerConfigJson
+	} else if _, ok := credential.Data[v1.DockerConfigKey]; ok {
+		credential.Type = v1.SecretTypeDockercfg
+	}
+
+	return credential, nil
+}
+
+// Changed reports whether the files must be read again, they are read again when the credential expires.
+func (s *fileSource) Changed(_ context.Context, credential *Credential) (bool, error) {
+	return credential.source != s.id() || credential.Expired(), nil
+}
+
+func (s *fileSource) id() string {
+	return "file/" + s.path
+}
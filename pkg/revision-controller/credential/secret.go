@@ -0,0 +1,48 @@
+package credential
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secretSource reads the credential from a Kubernetes Secret in the namespace of the function.
+type secretSource struct {
+	client.Client
+	name      string
+	namespace string
+}
+
+func (s *secretSource) Get(ctx context.Context) (*Credential, error) {
+	secret := &v1.Secret{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, secret); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Name:    secret.Name,
+		Type:    secret.Type,
+		Data:    secret.Data,
+		Version: secret.ResourceVersion,
+		source:  s.id(),
+	}, nil
+}
+
+// Changed reports whether the secret is changed, the secret is read from the cache of the client.
+func (s *secretSource) Changed(ctx context.Context, credential *Credential) (bool, error) {
+	if credential.source != s.id() {
+		return true, nil
+	}
+
+	secret := &v1.Secret{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, secret); err != nil {
+		return false, err
+	}
+
+	return secret.ResourceVersion != credential.Version, nil
+}
+
+func (s *secretSource) id() string {
+	return "secret/" + s.namespace + "/" + s.name
+}
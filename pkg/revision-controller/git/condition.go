@@ -0,0 +1,61 @@
+package git
+
+import (
+	"time"
+
+	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// unavailablePollingInterval is the minimal polling interval while the source is unavailable.
+	unavailablePollingInterval = 5 * time.Minute
+)
+
+// setCondition records the condition of the unavailable source in the function and emits an event,
+// nil clears the condition after the source is available again. The condition is recorded only when it changes.
+func (r *RevisionController) setCondition(sourceErr *provider.SourceError) {
+	reason := ""
+	var condition *rollout.Condition
+	if sourceErr != nil {
+		reason = sourceErr.Reason
+		condition = &rollout.Condition{Reason: reason, Message: sourceErr.Error()}
+	}
+
+	if r.conditionSynced && reason == r.conditionReason {
+		return
+	}
+
+	if err := rollout.SetCondition(r.Client, r.fn, r.historyType(), condition); err != nil {
+		r.log.Error(err, "record condition error")
+		return
+	}
+
+	if sourceErr != nil {
+		r.log.Error(sourceErr, "source is unavailable, slow down polling", "reason", reason, "pollingInterval", r.unavailableInterval())
+		r.recorder.Event(r.fn, v1.EventTypeWarning, reason, sourceErr.Error())
+
+		// Reload the credential once, the rotated credential is used at the next polling.
+		if reason == provider.ReasonAuthFailed {
+			r.credential = nil
+			if err := r.update(r.params); err != nil {
+				r.log.Error(err, "refresh credential error")
+			}
+		}
+	} else if r.conditionReason != "" {
+		r.log.Info("source is available again", "branch", r.gitProvider.Branch())
+	}
+
+	r.conditionReason = reason
+	r.conditionSynced = true
+}
+
+// unavailableInterval returns the polling interval while the source is unavailable.
+func (r *RevisionController) unavailableInterval() time.Duration {
+	if r.config.PollingInterval > unavailablePollingInterval {
+		return r.config.PollingInterval
+	}
+
+	return unavailablePollingInterval
+}
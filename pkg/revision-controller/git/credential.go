@@ -0,0 +1,102 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/credential"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
+)
+
+const (
+	username       = "username"
+	password       = "password"
+	token          = "token"
+	gitCredentials = ".git-credentials"
+	sshPrivateKey  = "ssh-privatekey"
+)
+
+// setCredentials fills the username and the password of the git config with the credential data.
+// The keys can be specified by the `username-key` and `password-key` params, otherwise the layouts used
+// by Shipwright are tried in order: `.git-credentials`, `username`/`password`, `password`, `token`.
+func setCredentials(gitConfig *provider.GitConfig, cred *credential.Credential, config map[string]string) error {
+	if config[constants.UsernameKey] != "" || config[constants.PasswordKey] != "" {
+		passwordKey := config[constants.PasswordKey]
+		if passwordKey == "" {
+			passwordKey = password
+		}
+
+		if _, ok := cred.Data[passwordKey]; !ok {
+			return fmt.Errorf("key %s not found in credential %s", passwordKey, cred.Name)
+		}
+
+		gitConfig.Username = string(cred.Data[config[constants.UsernameKey]])
+		gitConfig.Password = string(cred.Data[passwordKey])
+		return nil
+	}
+
+	if data, ok := cred.Data[gitCredentials]; ok {
+		return setGitCredentials(gitConfig, data)
+	}
+
+	if data, ok := cred.Data[password]; ok {
+		gitConfig.Username = string(cred.Data[username])
+		gitConfig.Password = string(data)
+		return nil
+	}
+
+	if data, ok := cred.Data[token]; ok {
+		gitConfig.Password = string(data)
+		return nil
+	}
+
+	if _, ok := cred.Data[sshPrivateKey]; ok {
+		return fmt.Errorf("ssh private key in credential %s can not be used to access the git provider api, "+
+			"a token or password is required", cred.Name)
+	}
+
+	return fmt.Errorf("no usable key found in credential %s", cred.Name)
+}
+
+// setGitCredentials parses the content of a `.git-credentials` file, the entry matching the host of
+// the git url is used, or the first entry if none matches.
+func setGitCredentials(gitConfig *provider.GitConfig, data []byte) error {
+	host := ""
+	if u, err := url.Parse(gitConfig.URL); err == nil {
+		host = u.Host
+	}
+
+	var found *url.URL
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		u, err := url.Parse(line)
+		if err != nil || u.User == nil {
+			continue
+		}
+
+		if found == nil || u.Host == host {
+			found = u
+		}
+
+		if u.Host == host {
+			break
+		}
+	}
+
+	if found == nil {
+		return fmt.Errorf("%s", "no credential found in .git-credentials")
+	}
+
+	gitConfig.Username = found.User.Username()
+	gitConfig.Password, _ = found.User.Password()
+	return nil
+}
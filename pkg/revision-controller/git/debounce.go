@@ -0,0 +1,73 @@
+package git
+
+import (
+	"time"
+
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+)
+
+const (
+	maxSeenHeads = 16
+)
+
+// observe records the time the heads are first seen and the time the head changes last,
+// at most maxSeenHeads heads are kept.
+func (r *RevisionController) observe(head string) {
+	now := time.Now()
+	if head != r.lastHead {
+		r.lastHead = head
+		r.lastChange = now
+	}
+
+	if _, ok := r.seenHeads[head]; ok {
+		return
+	}
+
+	if len(r.seenHeads) >= maxSeenHeads {
+		oldest := ""
+		for k, t := range r.seenHeads {
+			if oldest == "" || t.Before(r.seenHeads[oldest]) {
+				oldest = k
+			}
+		}
+		delete(r.seenHeads, oldest)
+	}
+
+	r.seenHeads[head] = now
+}
+
+// quiet returns true if the head is stable for the quiet period, otherwise a check is triggered
+// at the end of the quiet period, so that the latest head of a burst of pushes is built only.
+func (r *RevisionController) quiet() bool {
+	remaining := r.config.QuietPeriod - time.Since(r.lastChange)
+	if remaining <= 0 {
+		return true
+	}
+
+	if r.quietTimer != nil {
+		r.quietTimer.Stop()
+	}
+	r.quietTimer = time.AfterFunc(remaining, r.Trigger)
+	return false
+}
+
+// isStale returns true if the build of the current head is in progress and the head is seen before
+// the current head, such as the stale head returned by the cache of the git provider api.
+func (r *RevisionController) isStale(head string, currentHead string) (bool, error) {
+	headSeen, ok := r.seenHeads[head]
+	if !ok {
+		return false, nil
+	}
+
+	currentHeadSeen, ok := r.seenHeads[currentHead]
+	if !ok || !headSeen.Before(currentHeadSeen) {
+		return false, nil
+	}
+
+	function, err := r.getFunction()
+	if err != nil {
+		return false, err
+	}
+
+	return rollout.BuildInProgress(function), nil
+}
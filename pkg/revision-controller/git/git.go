@@ -2,10 +2,12 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,19 +15,21 @@ import (
 	openfunction "github.com/openfunction/apis/core/v1beta1"
 	"github.com/openfunction/revision-controller/pkg/constants"
 	revisioncontroller "github.com/openfunction/revision-controller/pkg/revision-controller"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/credential"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider/gitee"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider/github"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider/gitlab"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	password = "password"
-
 	gitProviderGithub = "github"
 	gitProviderGitlab = "gitlab"
 	gitProviderGitee  = "gitee"
@@ -33,27 +37,82 @@ const (
 
 type RevisionController struct {
 	client.Client
-	log    logr.Logger
-	fn     *openfunction.Function
-	config *Config
-
+	clientset kubernetes.Interface
+	recorder  record.EventRecorder
+	log       logr.Logger
+	fn        *openfunction.Function
+	config    *Config
+	// source is the name of the source watched.
+	source string
+	// lock serializes the checks and the updates of the params.
+	lock sync.Mutex
+
+	params      map[string]string
+	credential  *credential.Credential
 	gitConfig   *provider.GitConfig
 	gitProvider provider.GitProvider
 
+	// The polling is paused until the rate limit of the git provider api is reset,
+	// or slowed down while the source is unavailable.
+	backoffUntil time.Time
+	// The reason of the condition recorded, empty means the source is available.
+	conditionReason string
+	conditionSynced bool
+	// The branch watched, used to tell the renamed default branch.
+	branch string
+
+	// The last commit status reported, so that the same status is reported only once.
+	reportedStatus string
+	// The serving of the function before the build of the head, it's not the serving of the head.
+	staleHead    string
+	staleServing string
+
+	// The heads observed, used to debounce the changes and to tell the newer commits.
+	lastHead   string
+	lastChange time.Time
+	seenHeads  map[string]time.Time
+	quietTimer *time.Timer
+
 	stopCh chan os.Signal
+	// The triggers received during a check are merged by the buffer of the channel.
+	triggerCh chan struct{}
 }
 
 type Config struct {
 	RepoType        string
 	PollingInterval time.Duration
+	QuietPeriod     time.Duration
+	InitialRevision string
+	HistoryLimit    int
+	Rollback        *rollout.RollbackPolicy
+	Freeze          *rollout.Freeze
+	ManualApproval  bool
+	Schedule        *rollout.Schedule
+	// Preview is true if a preview function is created for each open pull request.
+	Preview bool
+	// PreviewForks is true if the pull requests from the forks are previewed too.
+	PreviewForks bool
+	// CommitStatus is true if the build and serving results are reported as the commit statuses, it is not supported by gitee.
+	CommitStatus        bool
+	CommitStatusContext string
 }
 
-func NewRevisionController(c client.Client, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
+func NewRevisionController(c client.Client, clientset kubernetes.Interface, recorder record.EventRecorder, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
+	source := config[constants.SourceName]
+	if source == "" {
+		source = constants.DefaultSourceName
+	}
+
 	r := &RevisionController{
-		Client: c,
-		log:    ctrl.Log.WithName("RevisionController").WithValues("Function", fn.Namespace+"/"+fn.Name, "Type", revisionControllerType),
-		fn:     fn,
-		stopCh: make(chan os.Signal),
+		Client:    c,
+		clientset: clientset,
+		recorder:  recorder,
+		log:       ctrl.Log.WithName("RevisionController").WithValues("Function", fn.Namespace+"/"+fn.Name, "Type", revisionControllerType, "Source", source),
+		fn:        fn,
+		source:    source,
+		stopCh:    make(chan os.Signal),
+		triggerCh: make(chan struct{}, 1),
+		seenHeads: make(map[string]time.Time),
 	}
 	signal.Notify(r.stopCh, os.Interrupt, syscall.SIGTERM)
 
@@ -63,32 +122,197 @@ func NewRevisionController(c client.Client, fn *openfunction.Function, revisionC
 		return nil, err
 	}
 
-	r.gitConfig, err = r.getGitConfig(config)
+	r.gitConfig, r.credential, err = r.getGitConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	r.gitProvider, err = newProvider(r.config.RepoType, r.gitConfig)
-	return r, err
+	r.params = config
+	r.gitProvider, err = r.newProvider(r.config.RepoType, r.gitConfig)
+	if err != nil {
+		// The revision controller is started in the unavailable state if the source is unavailable already.
+		sourceErr := &provider.SourceError{}
+		if !errors.As(err, &sourceErr) || r.gitProvider == nil {
+			return nil, err
+		}
+
+		r.backoffUntil = time.Now().Add(r.unavailableInterval())
+		r.setCondition(sourceErr)
+	}
+
+	return r, nil
 }
 
 func (r *RevisionController) Start() {
 	go func() {
 		compare := func() {
+			if r.credential.Expired() {
+				r.log.V(1).Info("credential expired, refresh git provider")
+				if err := r.update(r.params); err != nil {
+					r.log.Error(err, "refresh credential error")
+					return
+				}
+			}
+
+			if time.Now().Before(r.backoffUntil) {
+				return
+			}
+
+			if r.config.Preview {
+				prs, err := r.gitProvider.ListPullRequests()
+				if err != nil {
+					rateLimitErr := &provider.RateLimitError{}
+					if errors.As(err, &rateLimitErr) {
+						r.backoffUntil = rateLimitErr.Reset
+						r.log.Info("git provider api rate limit exceeded, pause polling until reset",
+							"reset", rateLimitErr.Reset, "anonymous", r.gitConfig.Anonymous())
+						return
+					}
+
+					r.log.Error(err, "list pull requests error")
+				} else if err := r.syncPreviews(prs); err != nil {
+					r.log.Error(err, "sync preview functions error")
+				}
+			}
+
+			if r.config.CommitStatus {
+				if err := r.reportStatus(); err != nil {
+					r.log.Error(err, "report commit status error")
+				}
+			}
+
+			if r.config.HistoryLimit > 0 {
+				revision, err := rollout.RecordOutcome(r.Client, r.fn, r.historyType(), rollout.BuildOutcome)
+				if err != nil {
+					r.log.Error(err, "record revision outcome error")
+				}
+
+				if r.config.Rollback.ShouldRollback(revision) {
+					if err := r.rollback(revision); err != nil {
+						r.log.Error(err, "rollback error")
+					}
+					return
+				}
+			}
+
+			// The original revision is not restored while the function is frozen.
+			if r.config.Rollback != nil && r.config.Freeze == nil {
+				inCooldown, err := r.checkRollback()
+				if err != nil {
+					r.log.Error(err, "check rollback error")
+					return
+				}
+
+				if inCooldown {
+					r.log.V(1).Info("function was rolled back, wait for the cooldown")
+					return
+				}
+			}
+
 			head, err := r.gitProvider.GetHead()
 			if err != nil {
+				rateLimitErr := &provider.RateLimitError{}
+				if errors.As(err, &rateLimitErr) {
+					r.backoffUntil = rateLimitErr.Reset
+					r.log.Info("git provider api rate limit exceeded, pause polling until reset",
+						"reset", rateLimitErr.Reset, "anonymous", r.gitConfig.Anonymous())
+					return
+				}
+
+				sourceErr := &provider.SourceError{}
+				if errors.As(err, &sourceErr) {
+					r.backoffUntil = time.Now().Add(r.unavailableInterval())
+					r.setCondition(sourceErr)
+					return
+				}
+
 				r.log.Error(err, "get git repository head error")
 				return
 			}
+			r.setCondition(nil)
+
+			if branch := r.gitProvider.Branch(); branch != r.branch {
+				if r.branch != "" {
+					r.log.Info("default branch renamed, follow the new default branch", "from", r.branch, "to", branch)
+					r.recorder.Event(r.fn, v1.EventTypeNormal, "DefaultBranchRenamed",
+						fmt.Sprintf("default branch is renamed from %s to %s", r.branch, branch))
+				}
+				r.branch = branch
+			}
 
+			r.observe(head)
 			currentHead, err := r.getCurrentHead()
+			if err != nil {
+				r.log.Error(err, "get current head error")
+				return
+			}
+
 			if currentHead == head {
 				r.log.V(1).Info("source code has no change")
 				return
 			}
 
 			if currentHead == "" {
-				r.log.V(1).Info("function was just created")
+				switch r.config.InitialRevision {
+				case constants.InitialRevisionWait:
+					r.log.V(1).Info("function was just created, wait for the first build")
+					return
+				case constants.InitialRevisionBaseline:
+					if err := r.recordBaseline(head); err != nil {
+						r.log.Error(err, "record baseline revision error")
+						return
+					}
+
+					r.log.Info("function was just created, record the baseline revision", "revision", head)
+					r.recordRevision(head, rollout.ReasonBaseline)
+					return
+				}
+			}
+
+			if reason := r.config.Freeze.Blocks(head); reason != "" {
+				if err := r.setPending(head, fmt.Sprintf("update available: revision %s, not applied because %s", head, reason)); err != nil {
+					r.log.Error(err, "record pending revision error")
+				}
+
+				r.log.V(1).Info("update available, not applied", "revision", head, "reason", reason)
+				return
+			}
+
+			if r.config.QuietPeriod > 0 && !r.quiet() {
+				r.log.V(1).Info("source code changed, wait for the quiet period", "revision", head, "quietPeriod", r.config.QuietPeriod)
+				return
+			}
+
+			stale, err := r.isStale(head, currentHead)
+			if err != nil {
+				r.log.Error(err, "check build state error")
+				return
+			}
+
+			if stale {
+				r.log.V(1).Info("the build of a newer commit is in progress, ignore the stale head", "revision", head, "building", currentHead)
+				return
+			}
+
+			if r.config.ManualApproval {
+				approved, err := r.approved(head)
+				if err != nil {
+					r.log.Error(err, "check approval error")
+					return
+				}
+
+				if !approved {
+					r.log.V(1).Info("source code changed, wait for approval", "revision", head)
+					return
+				}
+			}
+
+			if !r.config.Schedule.Allowed(time.Now()) {
+				if err := r.setPending(head, fmt.Sprintf("source code changed to %s, the rebuild is queued until the next maintenance window", head)); err != nil {
+					r.log.Error(err, "record pending revision error")
+				}
+
+				r.log.V(1).Info("source code changed, queued until the next maintenance window", "revision", head)
 				return
 			}
 
@@ -99,19 +323,35 @@ func (r *RevisionController) Start() {
 				return
 			}
 
+			if err := r.completeApproval(); err != nil {
+				r.log.Error(err, "clear approval error")
+			}
+
+			reason := rollout.ReasonChanged
+			if currentHead == "" {
+				reason = rollout.ReasonInitial
+			} else if r.config.ManualApproval {
+				reason = rollout.ReasonApproved
+			}
+			r.recordRevision(head, reason)
+
 			return
 		}
 
 		for {
+			r.lock.Lock()
+			compare()
+			interval := r.config.PollingInterval
+			r.lock.Unlock()
+
 			select {
 			case <-r.stopCh:
 				r.log.Info("revision controller stopped")
 				return
-			default:
+			case <-r.triggerCh:
+				r.log.V(1).Info("revision controller triggered")
+			case <-time.After(interval):
 			}
-
-			compare()
-			time.Sleep(r.config.PollingInterval)
 		}
 	}()
 
@@ -119,12 +359,20 @@ func (r *RevisionController) Start() {
 }
 
 func (r *RevisionController) Update(config map[string]string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.update(config)
+}
+
+// update applies the params, the lock must be held by the caller.
+func (r *RevisionController) update(config map[string]string) error {
 	revisionControllerConfig, err := r.getRevisionControllerConfig(config)
 	if err != nil {
 		return err
 	}
 
-	gitConfig, err := r.getGitConfig(config)
+	gitConfig, cred, err := r.getGitConfig(config)
 	if err != nil {
 		return err
 	}
@@ -132,24 +380,51 @@ func (r *RevisionController) Update(config map[string]string) error {
 	if revisionControllerConfig.RepoType != r.config.RepoType ||
 		!reflect.DeepEqual(r.gitConfig, gitConfig) {
 		r.log.Info("update git provider")
-		gp, err := newProvider(revisionControllerConfig.RepoType, gitConfig)
+		gp, err := r.newProvider(revisionControllerConfig.RepoType, gitConfig)
 		if err != nil {
-			return err
+			// The unavailable source is recorded by the next polling.
+			sourceErr := &provider.SourceError{}
+			if !errors.As(err, &sourceErr) || gp == nil {
+				return err
+			}
+
+			r.log.Info("git provider is updated, but the source is unavailable", "reason", sourceErr.Reason)
 		}
 
 		r.gitProvider = gp
 		r.gitConfig = gitConfig
+		r.branch = ""
 	}
 
+	// The previews are deleted when the preview is disabled.
+	if r.config.Preview && !revisionControllerConfig.Preview {
+		if err := r.syncPreviews(nil); err != nil {
+			return err
+		}
+	}
+
+	r.params = config
+	r.credential = cred
 	r.config = revisionControllerConfig
 	return nil
 }
 
 func (r *RevisionController) Stop() {
+	if r.quietTimer != nil {
+		r.quietTimer.Stop()
+	}
+
 	close(r.stopCh)
 	signal.Stop(r.stopCh)
 }
 
+func (r *RevisionController) Trigger() {
+	select {
+	case r.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
 func (r *RevisionController) getRevisionControllerConfig(config map[string]string) (*Config, error) {
 	interval := constants.DefaultPollingInterval
 	str := config[constants.PollingInterval]
@@ -164,45 +439,141 @@ func (r *RevisionController) getRevisionControllerConfig(config map[string]strin
 	revisionControllerConfig := &Config{
 		RepoType:        config[constants.RepoType],
 		PollingInterval: interval,
+		ManualApproval:  rollout.IsManualApproval(config),
+		Freeze:          rollout.NewFreeze(config),
+		// The previews are built from the pull requests of the source repository of the function.
+		Preview:      config[constants.Preview] == "true" && r.source == constants.DefaultSourceName,
+		PreviewForks: config[constants.PreviewForks] == "true",
+		// Only the source repository of the function is built.
+		CommitStatus:        config[constants.CommitStatus] == "true" && r.source == constants.DefaultSourceName,
+		CommitStatusContext: config[constants.CommitStatusContext],
+	}
+
+	if revisionControllerConfig.CommitStatusContext == "" {
+		revisionControllerConfig.CommitStatusContext = constants.DefaultStatusContext
+	}
+
+	if r.source != constants.DefaultSourceName {
+		source, err := getSource(config, r.source)
+		if err != nil {
+			return nil, err
+		}
+
+		if source.RepoType != "" {
+			revisionControllerConfig.RepoType = source.RepoType
+		}
 	}
 
 	if revisionControllerConfig.RepoType == "" {
 		revisionControllerConfig.RepoType = gitProviderGithub
 	}
 
+	// The commit status api is not provided by gitee.
+	if revisionControllerConfig.CommitStatus && revisionControllerConfig.RepoType == gitProviderGitee {
+		return nil, fmt.Errorf("%s", "unspport commit status for gitee")
+	}
+
+	if str := config[constants.QuietPeriod]; str != "" {
+		var err error
+		revisionControllerConfig.QuietPeriod, err = time.ParseDuration(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	revisionControllerConfig.InitialRevision, err = rollout.GetInitialRevisionPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.HistoryLimit, err = rollout.GetHistoryLimit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.Rollback, err = rollout.NewRollbackPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if revisionControllerConfig.Rollback != nil && revisionControllerConfig.HistoryLimit <= 0 {
+		return nil, fmt.Errorf("%s", "the revision history is required by the automatic rollback")
+	}
+
+	// Only the default source can be pinned by the rollback.
+	if r.source != constants.DefaultSourceName {
+		revisionControllerConfig.Rollback = nil
+	}
+
+	revisionControllerConfig.Schedule, err = rollout.NewSchedule(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return revisionControllerConfig, nil
 }
 
-func (r *RevisionController) getGitConfig(config map[string]string) (*provider.GitConfig, error) {
+func (r *RevisionController) getGitConfig(config map[string]string) (*provider.GitConfig, *credential.Credential, error) {
 	function, err := r.getFunction()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	gitConfig := &provider.GitConfig{}
 	gitConfig.URL = function.Spec.Build.SrcRepo.Url
 	gitConfig.Branch = function.Spec.Build.SrcRepo.Revision
+	// The source revision is pinned to the known-good commit during the rollback, the original one is watched.
+	if state, err := rollout.GetRollback(function, constants.RevisionControllerTypeSource); err == nil && state != nil {
+		gitConfig.Branch = state.Original
+	}
 	gitConfig.BaseURL = config[constants.BaseURL]
 	gitConfig.AuthType = config[constants.AuthType]
 	gitConfig.Project = config[constants.Project]
+	gitConfig.FollowDefaultBranch = config[constants.FollowDefaultBranch] == "true"
+	credentials := function.Spec.Build.SrcRepo.Credentials
 
-	if function.Spec.Build.SrcRepo.Credentials == nil {
-		return nil, fmt.Errorf("%s", "the source credential must be set")
+	if r.source != constants.DefaultSourceName {
+		source, err := getSource(config, r.source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		gitConfig.URL = source.URL
+		gitConfig.Branch = source.Revision
+		if source.BaseURL != "" {
+			gitConfig.BaseURL = source.BaseURL
+		}
+
+		credentials = nil
+		if source.Credentials != "" {
+			credentials = &v1.LocalObjectReference{Name: source.Credentials}
+		}
 	}
 
-	secret := &v1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      function.Spec.Build.SrcRepo.Credentials.Name,
-			Namespace: function.Namespace,
-		},
+	if config[constants.Anonymous] == "true" {
+		return gitConfig, nil, nil
 	}
 
-	if err := r.Get(context.Background(), client.ObjectKeyFromObject(secret), secret); err != nil {
-		return nil, err
+	source, err := credential.NewSource(r.Client, r.clientset, function.Namespace, credentials, config)
+	if err != nil {
+		// Fall back to anonymous access for public repositories.
+		if errors.Is(err, credential.ErrNotSpecified) {
+			return gitConfig, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	cred, err := credential.Refresh(context.Background(), source, r.credential)
+	if err != nil {
+		return nil, nil, err
 	}
-	gitConfig.Password = string(secret.Data[password])
 
-	return gitConfig, nil
+	if err := setCredentials(gitConfig, cred, config); err != nil {
+		return nil, nil, err
+	}
+
+	return gitConfig, cred, nil
 }
 
 func (r *RevisionController) getCurrentHead() (string, error) {
@@ -216,7 +587,7 @@ func (r *RevisionController) getCurrentHead() (string, error) {
 	}
 
 	for _, source := range function.Status.Sources {
-		if source.Name == "default" && source.Git != nil {
+		if source.Name == r.source && source.Git != nil {
 			return source.Git.CommitSha, nil
 		}
 	}
@@ -224,22 +595,83 @@ func (r *RevisionController) getCurrentHead() (string, error) {
 	return "", nil
 }
 
+// recordBaseline records the head as the source revision of the function without rebuilding it.
+func (r *RevisionController) recordBaseline(head string) error {
+	return rollout.PatchStatus(r.Client, r.fn, func(function *openfunction.Function) {
+		rollout.SetSource(function, gitSource(r.source, head))
+	})
+}
+
 func (r *RevisionController) updateFunctionStatus(head string) error {
-	function, err := r.getFunction()
-	if err != nil {
-		return err
+	return rollout.PatchStatus(r.Client, r.fn, func(function *openfunction.Function) {
+		// The serving is replaced after the build of the head.
+		r.staleHead = head
+		r.staleServing = ""
+		if function.Status.Serving != nil {
+			r.staleServing = function.Status.Serving.ResourceRef
+		}
+
+		function.Status.Build = nil
+		rollout.SetSource(function, gitSource(r.source, head))
+	})
+}
+
+// recordRevision records the head applied in the revision history of the function.
+func (r *RevisionController) recordRevision(head string, reason string) {
+	revision := rollout.Revision{
+		Type:     r.historyType(),
+		Revision: head,
+		Reason:   reason,
+	}
+	if err := rollout.RecordRevision(r.Client, r.fn, revision, r.config.HistoryLimit); err != nil {
+		r.log.Error(err, "record revision history error")
+	}
+}
+
+// historyType returns the type of the revisions in the history, the name of the source is appended
+// for the additional sources.
+func (r *RevisionController) historyType() string {
+	if r.source == constants.DefaultSourceName {
+		return constants.RevisionControllerTypeSource
 	}
 
-	function.Status.Build = nil
-	function.Status.Sources = nil
-	function.Status.Sources = append(function.Status.Sources, openfunction.SourceResult{
-		Name: "default",
+	return constants.RevisionControllerTypeSource + "/" + r.source
+}
+
+func gitSource(name string, head string) openfunction.SourceResult {
+	return openfunction.SourceResult{
+		Name: name,
 		Git: &openfunction.GitSourceResult{
 			CommitSha: head,
 		},
-	})
+	}
+}
+
+func (r *RevisionController) approved(head string) (bool, error) {
+	function, err := r.getFunction()
+	if err != nil {
+		return false, err
+	}
 
-	return r.Status().Update(context.Background(), function)
+	return rollout.Approved(r.Client, r.recorder, function, r.historyType(), head)
+}
+
+func (r *RevisionController) setPending(revision string, message string) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	return rollout.SetPending(r.Client, r.recorder, function, r.historyType(), revision, message)
+}
+
+func (r *RevisionController) completeApproval() error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	return rollout.Complete(r.Client, function, r.historyType())
 }
 
 func (r *RevisionController) getFunction() (*openfunction.Function, error) {
@@ -257,7 +689,13 @@ func (r *RevisionController) getFunction() (*openfunction.Function, error) {
 	return fn, nil
 }
 
-func newProvider(gitProvider string, config *provider.GitConfig) (provider.GitProvider, error) {
+func (r *RevisionController) newProvider(gitProvider string, config *provider.GitConfig) (provider.GitProvider, error) {
+	if config.Anonymous() {
+		r.log.Info("no source credential is used, access the git provider api anonymously, "+
+			"the api may be rate limited, consider setting a credential or increasing the polling interval",
+			"pollingInterval", r.config.PollingInterval)
+	}
+
 	var err error
 	var gp provider.GitProvider
 	switch gitProvider {
@@ -0,0 +1,268 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	k8sgatewayapiv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// syncPreviews creates a preview function for each open pull request, which is a copy of the function built
+// from the head of the pull request. The preview is updated when the pull request changes, and deleted
+// when the pull request is closed. The previews are owned by the function, so that they are deleted with it.
+// The pull requests from the forks are previewed only if `preview-forks` is true.
+func (r *RevisionController) syncPreviews(prs []provider.PullRequest) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	previews := &openfunction.FunctionList{}
+	if err := r.List(context.Background(), previews,
+		client.InNamespace(function.Namespace),
+		client.MatchingLabels{constants.PreviewOfLabel: function.Name}); err != nil {
+		return err
+	}
+
+	existing := make(map[string]*openfunction.Function)
+	for i := range previews.Items {
+		existing[previews.Items[i].Name] = &previews.Items[i]
+	}
+
+	opened := make(map[string]bool)
+	for _, pr := range prs {
+		if !r.config.PreviewForks && r.isFork(function, pr) {
+			r.log.V(1).Info("pull request is from a fork, skip preview", "pullRequest", pr.Number, "url", pr.URL)
+			continue
+		}
+
+		preview, err := r.newPreview(function, pr)
+		if err != nil {
+			return err
+		}
+		opened[preview.Name] = true
+
+		current, ok := existing[preview.Name]
+		if !ok {
+			if err := r.Create(context.Background(), preview); err != nil {
+				return err
+			}
+
+			r.log.Info("pull request opened, create preview function", "pullRequest", pr.Number, "preview", preview.Name, "revision", pr.Head)
+			r.recorder.Event(function, v1.EventTypeNormal, "PreviewCreated",
+				fmt.Sprintf("preview function %s is created for pull request %d", preview.Name, pr.Number))
+			continue
+		}
+
+		if current.Spec.Build != nil && current.Spec.Build.SrcRepo != nil &&
+			current.Spec.Build.SrcRepo.Revision != nil && *current.Spec.Build.SrcRepo.Revision == pr.Head {
+			continue
+		}
+
+		patch := client.MergeFrom(current.DeepCopy())
+		current.Spec = preview.Spec
+		if err := r.Patch(context.Background(), current, patch); err != nil {
+			return err
+		}
+
+		r.log.Info("pull request changed, update preview function", "pullRequest", pr.Number, "preview", current.Name, "revision", pr.Head)
+	}
+
+	for name, preview := range existing {
+		if opened[name] {
+			continue
+		}
+
+		if err := r.Delete(context.Background(), preview); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		r.log.Info("pull request closed, delete preview function", "preview", name)
+		r.recorder.Event(function, v1.EventTypeNormal, "PreviewDeleted",
+			fmt.Sprintf("preview function %s is deleted", name))
+	}
+
+	return nil
+}
+
+// newPreview returns the preview function of the pull request, the image is tagged with the number
+// of the pull request, so that the image of the function is not overwritten, and the route is published
+// on the hostnames of the pull request. The credentials, the service account and the secrets of the function
+// are not given to the preview of a fork, since the code of the fork is not trusted.
+func (r *RevisionController) newPreview(function *openfunction.Function, pr provider.PullRequest) (*openfunction.Function, error) {
+	spec := function.Spec.DeepCopy()
+	if spec.Build == nil || spec.Build.SrcRepo == nil {
+		return nil, fmt.Errorf("%s", "build must be set for the preview function")
+	}
+
+	if r.isFork(function, pr) {
+		spec.Build.SrcRepo.Credentials = nil
+		spec.ImageCredentials = nil
+		if spec.Serving != nil && spec.Serving.Template != nil {
+			removeSecrets(spec.Serving.Template)
+		}
+	}
+
+	spec.Route = previewRoute(spec.Route, pr.Number)
+
+	head := pr.Head
+	spec.Build.SrcRepo.Url = pr.URL
+	spec.Build.SrcRepo.Revision = &head
+
+	ref, err := name.ParseReference(spec.Image)
+	if err != nil {
+		return nil, err
+	}
+	spec.Image = ref.Context().Tag(fmt.Sprintf("pr-%d", pr.Number)).String()
+
+	preview := &openfunction.Function{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-pr-%d", function.Name, pr.Number),
+			Namespace: function.Namespace,
+			Labels: map[string]string{
+				constants.PreviewOfLabel:          function.Name,
+				constants.PreviewPullRequestLabel: strconv.Itoa(pr.Number),
+			},
+		},
+		Spec: *spec,
+	}
+
+	if err := controllerutil.SetControllerReference(function, preview, r.Scheme()); err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// isFork returns true if the pull request is not from the source repository of the function.
+func (r *RevisionController) isFork(function *openfunction.Function, pr provider.PullRequest) bool {
+	if function.Spec.Build == nil || function.Spec.Build.SrcRepo == nil {
+		return true
+	}
+
+	return !provider.SameRepository(pr.URL, function.Spec.Build.SrcRepo.Url)
+}
+
+// previewRoute returns the route of the preview, the hostnames of the function are prefixed with the number
+// of the pull request and the rules are dropped, so that the preview doesn't serve the requests of the function.
+// The default route is used if the function has no hostnames, which is published on the name of the preview.
+func previewRoute(route *openfunction.RouteImpl, number int) *openfunction.RouteImpl {
+	if route == nil || len(route.Hostnames) == 0 {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("pr-%d", number)
+	preview := &openfunction.RouteImpl{CommonRouteSpec: route.CommonRouteSpec}
+	for _, hostname := range route.Hostnames {
+		host := string(hostname)
+		if strings.HasPrefix(host, "*.") {
+			host = prefix + strings.TrimPrefix(host, "*")
+		} else {
+			host = prefix + "." + host
+		}
+		preview.Hostnames = append(preview.Hostnames, k8sgatewayapiv1alpha2.Hostname(host))
+	}
+
+	return preview
+}
+
+// removeSecrets removes the service account, the secret environments and the secret volumes from the pod.
+func removeSecrets(pod *v1.PodSpec) {
+	pod.ServiceAccountName = ""
+	pod.DeprecatedServiceAccount = ""
+	automount := false
+	pod.AutomountServiceAccountToken = &automount
+
+	volumes := make(map[string]bool)
+	var kept []v1.Volume
+	for _, volume := range pod.Volumes {
+		if isSecretVolume(volume) {
+			volumes[volume.Name] = true
+			continue
+		}
+		kept = append(kept, volume)
+	}
+	pod.Volumes = kept
+
+	removeContainerSecrets := func(container *v1.Container) {
+		var env []v1.EnvVar
+		for _, e := range container.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				continue
+			}
+			env = append(env, e)
+		}
+		container.Env = env
+
+		var envFrom []v1.EnvFromSource
+		for _, e := range container.EnvFrom {
+			if e.SecretRef != nil {
+				continue
+			}
+			envFrom = append(envFrom, e)
+		}
+		container.EnvFrom = envFrom
+
+		var mounts []v1.VolumeMount
+		for _, mount := range container.VolumeMounts {
+			if volumes[mount.Name] {
+				continue
+			}
+			mounts = append(mounts, mount)
+		}
+		container.VolumeMounts = mounts
+	}
+
+	for i := range pod.InitContainers {
+		removeContainerSecrets(&pod.InitContainers[i])
+	}
+	for i := range pod.Containers {
+		removeContainerSecrets(&pod.Containers[i])
+	}
+}
+
+// isSecretVolume returns true if the volume mounts a secret or a service account token.
+func isSecretVolume(volume v1.Volume) bool {
+	if volume.Secret != nil {
+		return true
+	}
+
+	if volume.Projected != nil {
+		for _, source := range volume.Projected.Sources {
+			if source.Secret != nil || source.ServiceAccountToken != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// DeletePreviews deletes the preview functions of the function, it's called when the previews are not
+// synced by the revision controller anymore, such as the revision controller is disabled.
+func DeletePreviews(c client.Client, function *openfunction.Function) error {
+	previews := &openfunction.FunctionList{}
+	if err := c.List(context.Background(), previews,
+		client.InNamespace(function.Namespace),
+		client.MatchingLabels{constants.PreviewOfLabel: function.Name}); err != nil {
+		return err
+	}
+
+	for i := range previews.Items {
+		if err := c.Delete(context.Background(), &previews.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
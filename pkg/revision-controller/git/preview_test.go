@@ -0,0 +1,97 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	k8sgatewayapiv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+func TestPreviewRoute(t *testing.T) {
+	gateway := openfunction.CommonRouteSpec{GatewayRef: &openfunction.GatewayRef{Name: "gateway"}}
+	tests := []struct {
+		name  string
+		route *openfunction.RouteImpl
+		want  *openfunction.RouteImpl
+	}{
+		{
+			name: "default route",
+		},
+		{
+			name: "no hostnames",
+			route: &openfunction.RouteImpl{
+				CommonRouteSpec: gateway,
+				Rules:           []k8sgatewayapiv1alpha2.HTTPRouteRule{{}},
+			},
+		},
+		{
+			name: "hostnames",
+			route: &openfunction.RouteImpl{
+				CommonRouteSpec: gateway,
+				Hostnames:       []k8sgatewayapiv1alpha2.Hostname{"hello.example.com", "*.example.org"},
+				Rules:           []k8sgatewayapiv1alpha2.HTTPRouteRule{{}},
+			},
+			want: &openfunction.RouteImpl{
+				CommonRouteSpec: gateway,
+				Hostnames:       []k8sgatewayapiv1alpha2.Hostname{"pr-1.hello.example.com", "pr-1.example.org"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewRoute(tt.route, 1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("previewRoute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveSecrets(t *testing.T) {
+	pod := &v1.PodSpec{
+		ServiceAccountName: "function",
+		Volumes: []v1.Volume{
+			{Name: "secret", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "secret"}}},
+			{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}},
+		},
+		Containers: []v1.Container{
+			{
+				Name: "function",
+				Env: []v1.EnvVar{
+					{Name: "PLAIN", Value: "value"},
+					{Name: "SECRET", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{Key: "key"}}},
+				},
+				EnvFrom: []v1.EnvFromSource{
+					{SecretRef: &v1.SecretEnvSource{}},
+					{ConfigMapRef: &v1.ConfigMapEnvSource{}},
+				},
+				VolumeMounts: []v1.VolumeMount{{Name: "secret"}, {Name: "config"}},
+			},
+		},
+	}
+
+	removeSecrets(pod)
+
+	if pod.ServiceAccountName != "" || pod.AutomountServiceAccountToken == nil || *pod.AutomountServiceAccountToken {
+		t.Errorf("service account is not removed, %s", pod.ServiceAccountName)
+	}
+
+	if len(pod.Volumes) != 1 || pod.Volumes[0].Name != "config" {
+		t.Errorf("secret volume is not removed, %v", pod.Volumes)
+	}
+
+	container := pod.Containers[0]
+	if len(container.Env) != 1 || container.Env[0].Name != "PLAIN" {
+		t.Errorf("secret env is not removed, %v", container.Env)
+	}
+
+	if len(container.EnvFrom) != 1 || container.EnvFrom[0].ConfigMapRef == nil {
+		t.Errorf("secret env from is not removed, %v", container.EnvFrom)
+	}
+
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].Name != "config" {
+		t.Errorf("secret volume mount is not removed, %v", container.VolumeMounts)
+	}
+}
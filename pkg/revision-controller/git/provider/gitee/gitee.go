@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"gitee.com/openeuler/go-gitee/gitee"
 	"github.com/antihax/optional"
@@ -27,22 +28,28 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 	}
 
 	conf := gitee.NewConfiguration()
-	conf.HTTPClient = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: config.Password},
-	))
+	if !config.Anonymous() {
+		conf.HTTPClient = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: config.Password},
+		))
+	}
 	p.client = gitee.NewAPIClient(conf)
 
-	url := config.URL
-	url = strings.TrimPrefix(url, "https://gitee.com/")
-	url = strings.TrimSuffix(url, ".git")
-	paths := strings.Split(url, "/")
-	p.owner = paths[0]
-	p.repo = paths[1]
+	var err error
+	p.owner, p.repo, err = provider.ParseRepository(config.URL)
+	if err != nil {
+		return nil, err
+	}
 
+	// The provider is returned with the source error if the repository or the branch is unavailable,
+	// so that the revision controller is started in the unavailable state.
 	if config.Branch == nil || *config.Branch == "" {
 		project, resp, err := p.client.RepositoriesApi.GetV5ReposOwnerRepo(context.Background(), p.owner, p.repo, &gitee.GetV5ReposOwnerRepoOpts{})
 		if err != nil {
-			return nil, err
+			if _, err := p.diagnose(resp, err); err != nil {
+				return p, err
+			}
+			return p, nil
 		}
 
 		if resp.StatusCode != http.StatusOK {
@@ -56,7 +63,10 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 
 	_, resp, err := p.client.RepositoriesApi.GetV5ReposOwnerRepoBranchesBranch(context.Background(), p.owner, p.repo, p.branch, nil)
 	if err != nil {
-		return nil, err
+		if _, err := p.diagnose(resp, err); err != nil {
+			return p, err
+		}
+		return p, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -72,11 +82,14 @@ func (p *Provider) GetHead() (string, error) {
 		PerPage: optional.NewInt32(1),
 	})
 	if err != nil {
-		return "", err
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			return "", &provider.RateLimitError{Reset: time.Now().Add(time.Minute), Err: err}
+		}
+		return p.diagnose(resp, err)
 	}
 
 	if resp != nil && resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%s", resp.Status)
+		return p.diagnose(resp, fmt.Errorf("%s", resp.Status))
 	}
 
 	if len(commits) == 0 {
@@ -85,3 +98,86 @@ func (p *Provider) GetHead() (string, error) {
 
 	return commits[0].Sha, nil
 }
+
+func (p *Provider) Branch() string {
+	return p.branch
+}
+
+// diagnose tells why the head can not be got, the renamed default branch is followed if it's enabled.
+func (p *Provider) diagnose(resp *http.Response, err error) (string, error) {
+	if resp == nil {
+		return "", err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", &provider.SourceError{Reason: provider.ReasonAuthFailed, Err: err}
+	case http.StatusNotFound:
+	default:
+		return "", err
+	}
+
+	project, resp, e := p.client.RepositoriesApi.GetV5ReposOwnerRepo(context.Background(), p.owner, p.repo, &gitee.GetV5ReposOwnerRepoOpts{})
+	if e != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", &provider.SourceError{Reason: provider.ReasonRepositoryNotFound, Err: err}
+		}
+		return "", err
+	}
+
+	if project.FullName != "" && !strings.EqualFold(project.FullName, p.owner+"/"+p.repo) {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryMoved, URL: project.HtmlUrl, Err: err}
+	}
+
+	if p.config.FollowDefaultBranch && (p.config.Branch == nil || *p.config.Branch == "") &&
+		project.DefaultBranch != "" && project.DefaultBranch != p.branch {
+		p.branch = project.DefaultBranch
+		return p.GetHead()
+	}
+
+	return "", &provider.SourceError{Reason: provider.ReasonBranchNotFound, Err: err}
+}
+
+func (p *Provider) ListPullRequests() ([]provider.PullRequest, error) {
+	var prs []provider.PullRequest
+	for page := int32(1); ; page++ {
+		items, resp, err := p.client.PullRequestsApi.GetV5ReposOwnerRepoPulls(context.Background(), p.owner, p.repo, &gitee.GetV5ReposOwnerRepoPullsOpts{
+			State:   optional.NewString("open"),
+			Page:    optional.NewInt32(page),
+			PerPage: optional.NewInt32(100),
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+				return nil, &provider.RateLimitError{Reset: time.Now().Add(time.Minute), Err: err}
+			}
+			return nil, err
+		}
+
+		if resp != nil && resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s", resp.Status)
+		}
+
+		for _, item := range items {
+			// The source repository of the pull request is deleted.
+			if item.Head == nil || item.Head.Repo == nil || item.Head.Repo.HtmlUrl == "" {
+				continue
+			}
+
+			prs = append(prs, provider.PullRequest{
+				Number: int(item.Number),
+				Head:   item.Head.Sha,
+				Branch: item.Head.Ref,
+				URL:    strings.TrimSuffix(item.Head.Repo.HtmlUrl, ".git") + ".git",
+			})
+		}
+
+		if len(items) < 100 {
+			return prs, nil
+		}
+	}
+}
+
+// SetCommitStatus is not supported, since the commit status api is not provided by gitee.
+func (p *Provider) SetCommitStatus(sha string, status provider.CommitStatus) error {
+	return fmt.Errorf("%s", "unspport commit status for gitee")
+}
@@ -2,15 +2,18 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/google/go-github/v49/github"
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
 	"golang.org/x/oauth2"
 )
 
+var errRedirected = fmt.Errorf("%s", "the api requests of the repository are redirected")
+
 type Provider struct {
 	config *provider.GitConfig
 	client *github.Client
@@ -18,27 +21,48 @@ type Provider struct {
 	owner  string
 	repo   string
 	branch string
+
+	// The api requests of the transferred or renamed repository are redirected permanently and followed
+	// by the client, the redirect is recorded to tell the repository is moved.
+	redirected bool
+	movedURL   string
 }
 
 func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 	p := &Provider{
 		config: config,
-		client: github.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+	}
+
+	httpClient := &http.Client{}
+	if config.Username != "" {
+		tp := &github.BasicAuthTransport{
+			Username: config.Username,
+			Password: config.Password,
+		}
+		httpClient = tp.Client()
+	} else if !config.Anonymous() {
+		httpClient = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: config.Password},
-		))),
+		))
 	}
+	httpClient.CheckRedirect = p.checkRedirect
+	p.client = github.NewClient(httpClient)
 
-	url := config.URL
-	url = strings.TrimPrefix(url, "https://github.com/")
-	url = strings.TrimSuffix(url, ".git")
-	paths := strings.Split(url, "/")
-	p.owner = paths[0]
-	p.repo = paths[1]
+	var err error
+	p.owner, p.repo, err = provider.ParseRepository(config.URL)
+	if err != nil {
+		return nil, err
+	}
 
+	// The provider is returned with the source error if the repository or the branch is unavailable,
+	// so that the revision controller is started in the unavailable state.
 	if config.Branch == nil || *config.Branch == "" {
 		repository, resp, err := p.client.Repositories.Get(context.Background(), p.owner, p.repo)
 		if err != nil {
-			return nil, err
+			if _, err := p.diagnose(resp, convertError(err)); err != nil {
+				return p, err
+			}
+			return p, nil
 		}
 
 		if resp.StatusCode != http.StatusOK {
@@ -56,17 +80,30 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 
 	_, resp, err := p.client.Repositories.GetBranch(context.Background(), p.owner, p.repo, p.branch, true)
 	if err != nil {
-		return nil, err
+		if _, err := p.diagnose(resp, convertError(err)); err != nil {
+			return p, err
+		}
+		return p, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("get branch error, %s", resp.Status)
 	}
 
+	if p.redirected {
+		if err := p.checkMoved(); err != nil {
+			return p, err
+		}
+	}
+
 	return p, nil
 }
 
 func (p *Provider) GetHead() (string, error) {
+	if p.movedURL != "" {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryMoved, URL: p.movedURL, Err: errRedirected}
+	}
+
 	commits, resp, err := p.client.Repositories.ListCommits(context.Background(), p.owner, p.repo, &github.CommitsListOptions{
 		SHA: p.branch,
 		ListOptions: github.ListOptions{
@@ -74,7 +111,7 @@ func (p *Provider) GetHead() (string, error) {
 		},
 	})
 	if err != nil {
-		return "", err
+		return p.diagnose(resp, convertError(err))
 	}
 
 	if resp != nil && resp.StatusCode != http.StatusOK {
@@ -85,5 +122,148 @@ func (p *Provider) GetHead() (string, error) {
 		return "", fmt.Errorf("%s", "no commit found")
 	}
 
+	if p.redirected {
+		if err := p.checkMoved(); err != nil {
+			return "", err
+		}
+	}
+
 	return *commits[0].SHA, nil
 }
+
+// checkRedirect records the permanent redirect and follows it as the default policy of the http client.
+func (p *Provider) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("%s", "stopped after 10 redirects")
+	}
+
+	if req.Response != nil && req.Response.StatusCode == http.StatusMovedPermanently {
+		p.redirected = true
+	}
+
+	return nil
+}
+
+// checkMoved returns an error if the repository is moved, the repository is got only after a redirect,
+// so that no api request is made on each polling.
+func (p *Provider) checkMoved() error {
+	p.redirected = false
+	repository, _, err := p.client.Repositories.Get(context.Background(), p.owner, p.repo)
+	if err != nil {
+		return convertError(err)
+	}
+
+	if !provider.SameRepository(p.config.URL, repository.GetCloneURL()) {
+		p.movedURL = repository.GetCloneURL()
+		return &provider.SourceError{Reason: provider.ReasonRepositoryMoved, URL: p.movedURL, Err: errRedirected}
+	}
+
+	return nil
+}
+
+func (p *Provider) Branch() string {
+	return p.branch
+}
+
+// diagnose tells why the head can not be got, the renamed default branch is followed if it's enabled.
+func (p *Provider) diagnose(resp *github.Response, err error) (string, error) {
+	if resp == nil {
+		return "", err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		rateLimitErr := &provider.RateLimitError{}
+		if errors.As(err, &rateLimitErr) {
+			return "", err
+		}
+
+		return "", &provider.SourceError{Reason: provider.ReasonAuthFailed, Err: err}
+	case http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity:
+	default:
+		return "", err
+	}
+
+	repository, resp, e := p.client.Repositories.Get(context.Background(), p.owner, p.repo)
+	if e != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", &provider.SourceError{Reason: provider.ReasonRepositoryNotFound, Err: err}
+		}
+		return "", err
+	}
+
+	if !provider.SameRepository(p.config.URL, repository.GetCloneURL()) {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryMoved, URL: repository.GetCloneURL(), Err: err}
+	}
+
+	if p.config.FollowDefaultBranch && (p.config.Branch == nil || *p.config.Branch == "") &&
+		repository.GetDefaultBranch() != "" && repository.GetDefaultBranch() != p.branch {
+		p.branch = repository.GetDefaultBranch()
+		return p.GetHead()
+	}
+
+	if repository.GetArchived() {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryArchived, Err: err}
+	}
+
+	return "", &provider.SourceError{Reason: provider.ReasonBranchNotFound, Err: err}
+}
+
+func (p *Provider) ListPullRequests() ([]provider.PullRequest, error) {
+	var prs []provider.PullRequest
+	opts := &github.PullRequestListOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	for {
+		items, resp, err := p.client.PullRequests.List(context.Background(), p.owner, p.repo, opts)
+		if err != nil {
+			return nil, convertError(err)
+		}
+
+		for _, item := range items {
+			// The source repository of the pull request is deleted.
+			if item.GetHead().GetRepo().GetCloneURL() == "" {
+				continue
+			}
+
+			prs = append(prs, provider.PullRequest{
+				Number: item.GetNumber(),
+				Head:   item.GetHead().GetSHA(),
+				Branch: item.GetHead().GetRef(),
+				URL:    item.GetHead().GetRepo().GetCloneURL(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			return prs, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *Provider) SetCommitStatus(sha string, status provider.CommitStatus) error {
+	_, _, err := p.client.Repositories.CreateStatus(context.Background(), p.owner, p.repo, sha, &github.RepoStatus{
+		State:       github.String(status.State),
+		Context:     github.String(status.Context),
+		Description: github.String(status.Description),
+	})
+
+	return convertError(err)
+}
+
+func convertError(err error) error {
+	rateLimitErr := &github.RateLimitError{}
+	if errors.As(err, &rateLimitErr) {
+		return &provider.RateLimitError{Reset: rateLimitErr.Rate.Reset.Time, Err: err}
+	}
+
+	abuseRateLimitErr := &github.AbuseRateLimitError{}
+	if errors.As(err, &abuseRateLimitErr) {
+		return &provider.RateLimitError{Reset: time.Now().Add(abuseRateLimitErr.GetRetryAfter()), Err: err}
+	}
+
+	return err
+}
@@ -3,12 +3,15 @@ package gitlab
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
 	"github.com/xanzy/go-gitlab"
 )
 
 const (
+	basicAuthType    = "BasicAuth"
 	jobTokenType     = "JobToken"
 	oauthTokenType   = "OAuthToken"
 	privateTokenType = "PrivateToken"
@@ -39,12 +42,17 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 		authType = privateTokenType
 	}
 	var err error
-	switch authType {
-	case jobTokenType:
+	switch {
+	case config.Anonymous():
+		p.client, err = gitlab.NewClient("", gitlab.WithBaseURL(config.BaseURL),
+			gitlab.WithHTTPClient(&http.Client{Transport: &anonymousTransport{}}))
+	case authType == basicAuthType:
+		p.client, err = gitlab.NewBasicAuthClient(config.Username, config.Password, gitlab.WithBaseURL(config.BaseURL))
+	case authType == jobTokenType:
 		p.client, err = gitlab.NewJobClient(config.Password, gitlab.WithBaseURL(config.BaseURL))
-	case oauthTokenType:
+	case authType == oauthTokenType:
 		p.client, err = gitlab.NewOAuthClient(config.Password, gitlab.WithBaseURL(config.BaseURL))
-	case privateTokenType:
+	case authType == privateTokenType:
 		p.client, err = gitlab.NewClient(config.Password, gitlab.WithBaseURL(config.BaseURL))
 	default:
 		return nil, fmt.Errorf("unspport auth type, %s", authType)
@@ -53,10 +61,15 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 		return nil, err
 	}
 
+	// The provider is returned with the source error if the project or the branch is unavailable,
+	// so that the revision controller is started in the unavailable state.
 	if config.Branch == nil || *config.Branch == "" {
 		repository, resp, err := p.client.Projects.GetProject(p.config.Project, nil)
 		if err != nil {
-			return nil, err
+			if _, err := p.diagnose(resp, convertError(resp, err)); err != nil {
+				return p, err
+			}
+			return p, nil
 		}
 
 		if resp.StatusCode != http.StatusOK {
@@ -70,7 +83,10 @@ func NewProvider(config *provider.GitConfig) (provider.GitProvider, error) {
 
 	_, resp, err := p.client.Branches.GetBranch(p.config.Project, p.branch)
 	if err != nil {
-		return nil, err
+		if _, err := p.diagnose(resp, convertError(resp, err)); err != nil {
+			return p, err
+		}
+		return p, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -89,7 +105,7 @@ func (p *Provider) GetHead() (string, error) {
 		},
 	})
 	if err != nil {
-		return "", err
+		return p.diagnose(resp, convertError(resp, err))
 	}
 
 	if resp != nil && resp.StatusCode != http.StatusOK {
@@ -102,3 +118,140 @@ func (p *Provider) GetHead() (string, error) {
 
 	return commits[0].ID, nil
 }
+
+func (p *Provider) Branch() string {
+	return p.branch
+}
+
+// diagnose tells why the head can not be got, the renamed default branch is followed if it's enabled.
+func (p *Provider) diagnose(resp *gitlab.Response, err error) (string, error) {
+	if resp == nil {
+		return "", err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", &provider.SourceError{Reason: provider.ReasonAuthFailed, Err: err}
+	case http.StatusNotFound:
+	default:
+		return "", err
+	}
+
+	project, resp, e := p.client.Projects.GetProject(p.config.Project, nil)
+	if e != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", &provider.SourceError{Reason: provider.ReasonRepositoryNotFound, Err: err}
+		}
+		return "", err
+	}
+
+	if p.config.URL != "" && !provider.SameRepository(p.config.URL, project.HTTPURLToRepo) &&
+		!provider.SameRepository(p.config.URL, project.SSHURLToRepo) {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryMoved, URL: project.HTTPURLToRepo, Err: err}
+	}
+
+	if p.config.FollowDefaultBranch && (p.config.Branch == nil || *p.config.Branch == "") &&
+		project.DefaultBranch != "" && project.DefaultBranch != p.branch {
+		p.branch = project.DefaultBranch
+		return p.GetHead()
+	}
+
+	if project.Archived {
+		return "", &provider.SourceError{Reason: provider.ReasonRepositoryArchived, Err: err}
+	}
+
+	return "", &provider.SourceError{Reason: provider.ReasonBranchNotFound, Err: err}
+}
+
+func (p *Provider) ListPullRequests() ([]provider.PullRequest, error) {
+	var prs []provider.PullRequest
+	// The git urls of the forks, keyed by the project id.
+	urls := make(map[int]string)
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		State: gitlab.String("opened"),
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+	for {
+		items, resp, err := p.client.MergeRequests.ListProjectMergeRequests(p.config.Project, opts)
+		if err != nil {
+			return nil, convertError(resp, err)
+		}
+
+		for _, item := range items {
+			url := p.config.URL
+			if item.SourceProjectID != item.TargetProjectID {
+				if _, ok := urls[item.SourceProjectID]; !ok {
+					project, resp, err := p.client.Projects.GetProject(item.SourceProjectID, nil)
+					if err != nil {
+						return nil, convertError(resp, err)
+					}
+					urls[item.SourceProjectID] = project.HTTPURLToRepo
+				}
+				url = urls[item.SourceProjectID]
+			}
+
+			prs = append(prs, provider.PullRequest{
+				Number: item.IID,
+				Head:   item.SHA,
+				Branch: item.SourceBranch,
+				URL:    url,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			return prs, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *Provider) SetCommitStatus(sha string, status provider.CommitStatus) error {
+	state := gitlab.Pending
+	switch status.State {
+	case provider.CommitStatusSuccess:
+		state = gitlab.Success
+	case provider.CommitStatusFailure:
+		state = gitlab.Failed
+	}
+
+	_, resp, err := p.client.Commits.SetCommitStatus(p.config.Project, sha, &gitlab.SetCommitStatusOptions{
+		State:       state,
+		Name:        gitlab.String(status.Context),
+		Description: gitlab.String(status.Description),
+	})
+	if err != nil {
+		return convertError(resp, err)
+	}
+
+	return nil
+}
+
+func convertError(resp *gitlab.Response, err error) error {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+
+	reset := time.Now().Add(time.Minute)
+	if v := resp.Header.Get("RateLimit-Reset"); v != "" {
+		if sec, e := strconv.ParseInt(v, 10, 64); e == nil {
+			reset = time.Unix(sec, 0)
+		}
+	}
+
+	return &provider.RateLimitError{Reset: reset, Err: err}
+}
+
+// anonymousTransport removes the empty token header set by the gitlab client,
+// so that the public projects can be accessed without credential.
+type anonymousTransport struct{}
+
+func (t *anonymousTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("PRIVATE-TOKEN") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Del("PRIVATE-TOKEN")
+	}
+
+	return http.DefaultTransport.RoundTrip(req)
+}
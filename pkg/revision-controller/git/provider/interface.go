@@ -1,14 +1,151 @@
 package provider
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
 type GitProvider interface {
 	GetHead() (string, error)
+	// Branch returns the branch watched, it changes if the default branch is renamed and followed.
+	Branch() string
+	// ListPullRequests returns the open pull requests or merge requests of the repository.
+	ListPullRequests() ([]PullRequest, error)
+	// SetCommitStatus reports the status of the commit to the git provider.
+	SetCommitStatus(sha string, status CommitStatus) error
+}
+
+const (
+	CommitStatusPending = "pending"
+	CommitStatusSuccess = "success"
+	CommitStatusFailure = "failure"
+)
+
+// CommitStatus is the status of a commit, the State is one of pending, success and failure.
+type CommitStatus struct {
+	State       string
+	Context     string
+	Description string
+}
+
+// PullRequest is an open pull request or merge request.
+type PullRequest struct {
+	Number int
+	// Head is the commit sha of the head of the pull request.
+	Head string
+	// Branch is the source branch of the pull request.
+	Branch string
+	// URL is the git url of the source repository, it's a fork of the repository if the pull request is from a fork.
+	URL string
 }
 
 type GitConfig struct {
 	URL      string
 	Branch   *string
+	Username string
 	Password string
 	AuthType string
 	BaseURL  string
 	Project  string
+	// FollowDefaultBranch is true if the renamed default branch is followed, it works only if the branch is not specified.
+	FollowDefaultBranch bool
+}
+
+// Anonymous reports whether the git provider api is accessed without credential.
+func (c *GitConfig) Anonymous() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+// RateLimitError is returned when the rate limit of the git provider api is exceeded.
+type RateLimitError struct {
+	Reset time.Time
+	Err   error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, reset at %s, %v", e.Reset.Format(time.RFC3339), e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	ReasonBranchNotFound     = "BranchNotFound"
+	ReasonRepositoryNotFound = "RepositoryNotFound"
+	ReasonRepositoryMoved    = "RepositoryMoved"
+	ReasonRepositoryArchived = "RepositoryArchived"
+	ReasonAuthFailed         = "AuthFailed"
+)
+
+// SourceError is returned when the watched branch or repository is unavailable, the head can not be got
+// until the function or the repository is fixed.
+type SourceError struct {
+	Reason string
+	// URL is the new url of the repository if it's moved.
+	URL string
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	if e.URL != "" {
+		return fmt.Sprintf("%s, the repository is moved to %s, %v", e.Reason, e.URL, e.Err)
+	}
+
+	return fmt.Sprintf("%s, %v", e.Reason, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// SameRepository reports whether the git urls point to the same repository, the scheme, the host
+// and the `.git` suffix are ignored.
+func SameRepository(url1 string, url2 string) bool {
+	path := func(gitURL string) string {
+		if u, err := url.Parse(gitURL); err == nil && strings.Contains(gitURL, "://") {
+			gitURL = u.Path
+		} else if index := strings.Index(gitURL, ":"); index > 0 {
+			gitURL = gitURL[index+1:]
+		}
+
+		return strings.ToLower(strings.TrimSuffix(strings.Trim(gitURL, "/"), ".git"))
+	}
+
+	return path(url1) == path(url2)
+}
+
+// ParseRepository returns the owner and the name of the repository from a git url,
+// both `https://host/owner/repo.git` and `git@host:owner/repo.git` are supported,
+// the owner of a project in the nested GitLab groups is the full path of the groups.
+func ParseRepository(gitURL string) (string, string, error) {
+	path := ""
+	if strings.Contains(gitURL, "://") {
+		u, err := url.Parse(gitURL)
+		if err != nil {
+			return "", "", err
+		}
+		path = u.Path
+	} else if index := strings.Index(gitURL, ":"); index > 0 {
+		path = gitURL[index+1:]
+	} else {
+		return "", "", fmt.Errorf("invalid git url, %s", gitURL)
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	paths := strings.Split(path, "/")
+	if len(paths) < 2 {
+		return "", "", fmt.Errorf("invalid git url, %s", gitURL)
+	}
+
+	for _, item := range paths {
+		if item == "" {
+			return "", "", fmt.Errorf("invalid git url, %s", gitURL)
+		}
+	}
+
+	return strings.Join(paths[:len(paths)-1], "/"), paths[len(paths)-1], nil
 }
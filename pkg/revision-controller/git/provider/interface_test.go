@@ -0,0 +1,65 @@
+package provider
+
+import "testing"
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		repo  string
+		err   bool
+	}{
+		{url: "https://github.com/owner/repo.git", owner: "owner", repo: "repo"},
+		{url: "https://github.com/owner/repo", owner: "owner", repo: "repo"},
+		{url: "https://github.com/owner/repo/", owner: "owner", repo: "repo"},
+		{url: "ssh://git@github.com/owner/repo.git", owner: "owner", repo: "repo"},
+		{url: "ssh://git@github.com:22/owner/repo.git", owner: "owner", repo: "repo"},
+		{url: "git@github.com:owner/repo.git", owner: "owner", repo: "repo"},
+		{url: "git@github.com:owner/repo", owner: "owner", repo: "repo"},
+		{url: "https://gitlab.com/group/subgroup/repo.git", owner: "group/subgroup", repo: "repo"},
+		{url: "git@gitlab.com:group/subgroup/repo.git", owner: "group/subgroup", repo: "repo"},
+		{url: "https://github.com/repo.git", err: true},
+		{url: "https://github.com/owner//repo.git", err: true},
+		{url: "github.com/owner/repo", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, err := ParseRepository(tt.url)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s/%s", owner, repo)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if owner != tt.owner || repo != tt.repo {
+				t.Fatalf("expected %s/%s, got %s/%s", tt.owner, tt.repo, owner, repo)
+			}
+		})
+	}
+}
+
+func TestSameRepository(t *testing.T) {
+	tests := []struct {
+		url1 string
+		url2 string
+		same bool
+	}{
+		{url1: "https://github.com/owner/repo.git", url2: "https://github.com/owner/repo", same: true},
+		{url1: "https://github.com/Owner/Repo.git", url2: "git@github.com:owner/repo.git", same: true},
+		{url1: "ssh://git@github.com/owner/repo.git", url2: "https://github.com/owner/repo/", same: true},
+		{url1: "https://github.com/owner/repo.git", url2: "https://github.com/fork/repo.git"},
+		{url1: "https://gitlab.com/group/subgroup/repo.git", url2: "https://gitlab.com/group/repo.git"},
+	}
+
+	for _, tt := range tests {
+		if same := SameRepository(tt.url1, tt.url2); same != tt.same {
+			t.Errorf("SameRepository(%s, %s) = %v, expected %v", tt.url1, tt.url2, same, tt.same)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package git
+
+import (
+	"context"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rollback pins the source revision of the function to the last known-good commit and rebuilds it.
+// The original source revision is kept in the rollback state, so that it can be restored after the cooldown.
+func (r *RevisionController) rollback(failed *rollout.Revision) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	good, err := rollout.LastGood(function, constants.RevisionControllerTypeSource, failed.Revision)
+	if err != nil {
+		return err
+	}
+
+	if good == nil {
+		r.log.Info("build of the new revision failed, no known-good revision to roll back to", "revision", failed.Revision)
+		r.recorder.Eventf(function, v1.EventTypeWarning, "RollbackFailed",
+			"build of revision %s failed, no known-good revision to roll back to", failed.Revision)
+		return nil
+	}
+
+	if reason := r.config.Freeze.Blocks(good.Revision); reason != "" {
+		r.log.Info("build of the new revision failed, not rolled back", "revision", failed.Revision, "reason", reason)
+		r.recorder.Eventf(function, v1.EventTypeWarning, "RollbackBlocked",
+			"build of revision %s failed, not rolled back to %s because %s", failed.Revision, good.Revision, reason)
+		return nil
+	}
+
+	state := r.config.Rollback.NewState(good, failed)
+	state.Original = function.Spec.Build.SrcRepo.Revision
+	if current, err := rollout.GetRollback(function, constants.RevisionControllerTypeSource); err == nil && current != nil {
+		state.Original = current.Original
+	}
+
+	if err := rollout.SetRollback(r.Client, r.fn, constants.RevisionControllerTypeSource, state); err != nil {
+		return err
+	}
+
+	if err := r.setSourceRevision(&good.Revision); err != nil {
+		return err
+	}
+
+	if err := r.updateFunctionStatus(good.Revision); err != nil {
+		return err
+	}
+
+	r.log.Info("build of the new revision failed, roll back to the known-good revision",
+		"failed", failed.Revision, "revision", good.Revision, "until", state.Until)
+	r.recorder.Eventf(function, v1.EventTypeWarning, "RevisionRolledBack",
+		"build of revision %s %s, rolled back to %s, the new revision will be retried after %s",
+		failed.Revision, failed.Outcome, good.Revision, state.Until)
+	r.recordRevision(good.Revision, rollout.ReasonRollback)
+	return nil
+}
+
+// checkRollback returns true if the function is rolled back and the cooldown is not over,
+// after the cooldown, the original source revision is restored so that the new revision is retried.
+func (r *RevisionController) checkRollback() (bool, error) {
+	function, err := r.getFunction()
+	if err != nil {
+		return false, err
+	}
+
+	state, err := rollout.GetRollback(function, constants.RevisionControllerTypeSource)
+	if err != nil || state == nil {
+		return false, err
+	}
+
+	if state.InCooldown() {
+		return true, nil
+	}
+
+	if err := r.setSourceRevision(state.Original); err != nil {
+		return false, err
+	}
+
+	if err := rollout.SetRollback(r.Client, r.fn, constants.RevisionControllerTypeSource, nil); err != nil {
+		return false, err
+	}
+
+	r.log.Info("rollback cooldown is over, retry the new revision", "failed", state.Failed)
+	return false, nil
+}
+
+func (r *RevisionController) setSourceRevision(revision *string) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(function.DeepCopy())
+	function.Spec.Build.SrcRepo.Revision = revision
+	return r.Patch(context.Background(), function, patch)
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/utils"
+)
+
+// Source is an additional source of the function, such as a shared library repository or a config repository,
+// the sources are defined by the `sources` param in yaml, and watched independently of the source repository
+// of the function which is the `default` source.
+type Source struct {
+	Name        string  `yaml:"name"`
+	URL         string  `yaml:"url"`
+	Revision    *string `yaml:"revision,omitempty"`
+	Credentials string  `yaml:"credentials,omitempty"`
+	RepoType    string  `yaml:"repo-type,omitempty"`
+	BaseURL     string  `yaml:"base-url,omitempty"`
+}
+
+// GetSources returns the additional sources defined by the `sources` param.
+func GetSources(config map[string]string) ([]Source, error) {
+	var sources []Source
+	if config[constants.Sources] == "" {
+		return sources, nil
+	}
+
+	if err := utils.YamlUnmarshal([]byte(config[constants.Sources]), &sources); err != nil {
+		return nil, fmt.Errorf("sources must be a yaml list of the sources, %s", err)
+	}
+
+	names := make(map[string]bool)
+	for _, source := range sources {
+		if source.Name == "" || source.URL == "" {
+			return nil, fmt.Errorf("%s", "name and url must be set for the source")
+		}
+
+		if source.Name == constants.DefaultSourceName || names[source.Name] {
+			return nil, fmt.Errorf("duplicate source name, %s", source.Name)
+		}
+		names[source.Name] = true
+	}
+
+	return sources, nil
+}
+
+func getSource(config map[string]string, name string) (*Source, error) {
+	sources, err := GetSources(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		if source.Name == name {
+			return &source, nil
+		}
+	}
+
+	return nil, fmt.Errorf("source %s not found", name)
+}
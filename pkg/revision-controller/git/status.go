@@ -0,0 +1,87 @@
+package git
+
+import (
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/git/provider"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+)
+
+// reportStatus reports the build and serving results of the current head of the function as the commit status,
+// a status is reported only once.
+func (r *RevisionController) reportStatus() error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	head := ""
+	for _, source := range function.Status.Sources {
+		if source.Name == r.source && source.Git != nil {
+			head = source.Git.CommitSha
+		}
+	}
+	if head == "" {
+		return nil
+	}
+
+	// The serving before the build of the head is recorded, so that its state is not reported for the head.
+	if head != r.staleHead && (function.Status.Build == nil || rollout.BuildInProgress(function)) {
+		r.staleHead = head
+		r.staleServing = ""
+		if function.Status.Serving != nil {
+			r.staleServing = function.Status.Serving.ResourceRef
+		}
+	}
+
+	staleServing := ""
+	if head == r.staleHead {
+		staleServing = r.staleServing
+	}
+
+	status := commitStatus(function, staleServing)
+	status.Context = r.config.CommitStatusContext
+	key := head + "/" + status.State + "/" + status.Description
+	if key == r.reportedStatus {
+		return nil
+	}
+
+	// The status is remembered only after it's reported, so that the failed report is retried.
+	if err := r.gitProvider.SetCommitStatus(head, status); err != nil {
+		return err
+	}
+	r.reportedStatus = key
+
+	r.log.V(1).Info("commit status reported", "revision", head, "state", status.State, "description", status.Description)
+	return nil
+}
+
+// commitStatus returns the status of the function, it's pending until the serving is running
+// or the build or the serving fails. The stale serving is the serving of the older revision,
+// it's pending until the serving of the new build replaces it.
+func commitStatus(function *openfunction.Function, staleServing string) provider.CommitStatus {
+	if function.Status.Build == nil {
+		return provider.CommitStatus{State: provider.CommitStatusPending, Description: "function is waiting for the build"}
+	}
+
+	if rollout.BuildInProgress(function) {
+		return provider.CommitStatus{State: provider.CommitStatusPending, Description: "function is being built"}
+	}
+
+	switch function.Status.Build.State {
+	case openfunction.Failed, openfunction.Timeout, openfunction.Canceled:
+		return provider.CommitStatus{State: provider.CommitStatusFailure, Description: "function build " + function.Status.Build.State}
+	}
+
+	if function.Status.Serving == nil || (staleServing != "" && function.Status.Serving.ResourceRef == staleServing) {
+		return provider.CommitStatus{State: provider.CommitStatusPending, Description: "function is starting"}
+	}
+
+	switch rollout.ServingOutcome(function) {
+	case openfunction.Running:
+		return provider.CommitStatus{State: provider.CommitStatusSuccess, Description: "function is running"}
+	case openfunction.Failed:
+		return provider.CommitStatus{State: provider.CommitStatusFailure, Description: "function serving failed"}
+	default:
+		return provider.CommitStatus{State: provider.CommitStatusPending, Description: "function is starting"}
+	}
+}
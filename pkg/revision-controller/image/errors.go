@@ -0,0 +1,137 @@
+package image
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	minRateLimitBackoff = time.Minute
+	maxRateLimitBackoff = 30 * time.Minute
+)
+
+// errorClass is the class of the errors returned by the image registry.
+type errorClass string
+
+const (
+	errorClassNone        errorClass = ""
+	errorClassAuth        errorClass = "auth"
+	errorClassNotFound    errorClass = "not-found"
+	errorClassRateLimited errorClass = "rate-limited"
+	errorClassTransient   errorClass = "transient"
+	errorClassUnknown     errorClass = "unknown"
+)
+
+// classifyError classifies the error returned by the image registry.
+func classifyError(err error) errorClass {
+	if err == nil {
+		return errorClassNone
+	}
+
+	transportErr := &transport.Error{}
+	if errors.As(err, &transportErr) {
+		for _, diagnostic := range transportErr.Errors {
+			switch diagnostic.Code {
+			case transport.UnauthorizedErrorCode, transport.DeniedErrorCode:
+				return errorClassAuth
+			case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode:
+				return errorClassNotFound
+			case transport.TooManyRequestsErrorCode:
+				return errorClassRateLimited
+			}
+		}
+
+		switch {
+		case transportErr.StatusCode == http.StatusUnauthorized, transportErr.StatusCode == http.StatusForbidden:
+			return errorClassAuth
+		case transportErr.StatusCode == http.StatusNotFound:
+			return errorClassNotFound
+		case transportErr.StatusCode == http.StatusTooManyRequests:
+			return errorClassRateLimited
+		case transportErr.StatusCode >= http.StatusInternalServerError, transportErr.Temporary():
+			return errorClassTransient
+		}
+
+		return errorClassUnknown
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return errorClassTransient
+	}
+
+	return errorClassUnknown
+}
+
+// isHeadUnsupported returns true if the error means the registry can not resolve the digest with HEAD,
+// such as the registries which do not return the `Docker-Content-Digest` header or do not allow HEAD.
+func isHeadUnsupported(err error) bool {
+	transportErr := &transport.Error{}
+	if errors.As(err, &transportErr) {
+		return transportErr.StatusCode == http.StatusMethodNotAllowed ||
+			transportErr.StatusCode == http.StatusNotImplemented ||
+			transportErr.StatusCode == http.StatusBadRequest
+	}
+
+	return classifyError(err) == errorClassUnknown
+}
+
+// handleError reports the error of accessing the image registry according to its class.
+// The auth and not found errors are reported once until they are resolved, the transient errors are
+// retried at the next polling silently. The keychain is rebuilt once on auth errors to pick up the
+// rotated credentials, and the polling backs off exponentially while the registry is rate limited.
+func (r *RevisionController) handleError(err error, msg string) {
+	class := classifyError(err)
+	reported := class == r.lastErrorClass
+	r.lastErrorClass = class
+
+	switch class {
+	case errorClassAuth:
+		if reported {
+			return
+		}
+
+		r.log.Error(err, msg+", authentication failed, check the image credentials")
+		r.recorder.Event(r.fn, v1.EventTypeWarning, "ImageRegistryAuthFailed", err.Error())
+		// The cached credential is dropped, so that it's got from the source again.
+		r.credential = nil
+		if err := r.update(r.params); err != nil {
+			r.log.Error(err, "refresh keychain error")
+		}
+	case errorClassNotFound:
+		if !reported {
+			r.log.Info(msg+", image not found, wait for it to be pushed", "error", err.Error())
+		}
+	case errorClassRateLimited:
+		if !reported || r.rateLimitBackoff == 0 {
+			r.rateLimitBackoff = minRateLimitBackoff
+			if r.config.PollingInterval > r.rateLimitBackoff {
+				r.rateLimitBackoff = r.config.PollingInterval
+			}
+		} else {
+			r.rateLimitBackoff *= 2
+			if r.rateLimitBackoff > maxRateLimitBackoff {
+				r.rateLimitBackoff = maxRateLimitBackoff
+			}
+		}
+		r.backoffUntil = time.Now().Add(r.rateLimitBackoff)
+
+		r.log.Info("image registry rate limit exceeded, pause polling, consider increasing the polling interval or setting a credential",
+			"pollingInterval", r.config.PollingInterval, "backoff", r.rateLimitBackoff)
+	case errorClassTransient:
+		r.log.V(1).Info(msg+", retry at next polling", "error", err.Error())
+	default:
+		r.log.Error(err, msg)
+	}
+}
@@ -3,53 +3,114 @@ package image
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/name"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	openfunction "github.com/openfunction/apis/core/v1beta1"
 	"github.com/openfunction/revision-controller/pkg/constants"
 	revisioncontroller "github.com/openfunction/revision-controller/pkg/revision-controller"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/credential"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+	"github.com/openfunction/revision-controller/pkg/utils"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type RevisionController struct {
 	client.Client
-	log      logr.Logger
-	fn       *openfunction.Function
-	config   *Config
-	keychain authn.Keychain
+	clientset  kubernetes.Interface
+	recorder   record.EventRecorder
+	log        logr.Logger
+	fn         *openfunction.Function
+	config     *Config
+	params     map[string]string
+	credential *credential.Credential
+	keychain   authn.Keychain
+	anonymous  bool
+	verifier   *verifier
+
+	// The digest refused by the verifier, so that the failure is reported only once.
+	rejectedDigest string
+	// The class of the last registry error, so that the same error is reported only once.
+	lastErrorClass errorClass
+	// The polling is paused until the backoff of the rate limit ends.
+	backoffUntil     time.Time
+	rateLimitBackoff time.Duration
+	// The registries which can not resolve the digest with HEAD, the manifest is fetched with GET instead.
+	headUnsupported map[string]bool
+	// The function is seen being rebuilt, so that the image pushed by the build is not redeployed again.
+	rebuilding bool
+
+	// lock serializes the checks and the updates of the params.
+	lock sync.Mutex
+	// watched is the snapshot of the image watched, it's read by the webhook receivers.
+	watched     *watchedImage
+	watchedLock sync.RWMutex
 
 	stopCh chan os.Signal
+	// The triggers received during a check are merged by the buffer of the channel.
+	triggerCh chan struct{}
+}
+
+// watchedImage is the image watched and the references of it on the mirrors.
+type watchedImage struct {
+	ref       name.Reference
+	refs      []name.Reference
+	tagPolicy *tagPolicy
 }
 
 type Config struct {
 	RevisionControllerType string
 	PollingInterval        time.Duration
+	InitialRevision        string
+	HistoryLimit           int
+	Rollback               *rollout.RollbackPolicy
+	Freeze                 *rollout.Freeze
+	ManualApproval         bool
+	Schedule               *rollout.Schedule
+	// WaitForBuild is true if the image is built by the function, the serving is restarted by the build
+	// after the image is pushed.
+	WaitForBuild bool
 	imageConfig
 }
 
 type imageConfig struct {
-	image      string
-	insecure   bool
-	credential *v1.LocalObjectReference
+	image          string
+	insecure       bool
+	credential     *v1.LocalObjectReference
+	pullSecrets    []string
+	serviceAccount string
+	cloudKeychains []string
+	tagPolicy      *tagPolicy
+	platform       *gcrv1.Platform
+	pinDigest      bool
+	mirrors        map[string][]string
+	transport      http.RoundTripper
 }
 
-func NewRevisionController(c client.Client, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
+func NewRevisionController(c client.Client, clientset kubernetes.Interface, recorder record.EventRecorder, fn *openfunction.Function, revisionControllerType string, config map[string]string) (revisioncontroller.RevisionController, error) {
 	r := &RevisionController{
-		Client: c,
-		log:    ctrl.Log.WithName("RevisionController").WithValues("Function", fn.Namespace+"/"+fn.Name, "Type", revisionControllerType),
-		fn:     fn,
-		stopCh: make(chan os.Signal),
+		Client:    c,
+		clientset: clientset,
+		recorder:  recorder,
+		log:       ctrl.Log.WithName("RevisionController").WithValues("Function", fn.Namespace+"/"+fn.Name, "Type", revisionControllerType),
+		fn:        fn,
+		stopCh:    make(chan os.Signal),
+		triggerCh: make(chan struct{}, 1),
+
+		headUnsupported: make(map[string]bool),
 	}
 	signal.Notify(r.stopCh, os.Interrupt, syscall.SIGTERM)
 
@@ -59,47 +120,238 @@ func NewRevisionController(c client.Client, fn *openfunction.Function, revisionC
 		return nil, err
 	}
 
-	r.keychain, err = r.getKeychain(r.config)
+	r.keychain, r.credential, err = r.getKeychain(r.config, config)
+	if err != nil {
+		return nil, err
+	}
+
+	r.verifier, err = r.getVerifier(config)
 	if err != nil {
 		return nil, err
 	}
 
+	r.params = config
+	r.setWatched()
 	return r, err
 }
 
 func (r *RevisionController) Start() {
 	go func() {
 		compare := func() {
-			digest, err := r.getLatestImageDigest()
+			if r.credential.Expired() {
+				r.log.V(1).Info("credential expired, refresh keychain")
+				if err := r.update(r.params); err != nil {
+					r.log.Error(err, "refresh credential error")
+					return
+				}
+			}
+
+			if time.Now().Before(r.backoffUntil) {
+				return
+			}
+
+			if r.config.HistoryLimit > 0 {
+				revision, err := rollout.RecordOutcome(r.Client, r.fn, r.config.RevisionControllerType, r.outcome)
+				if err != nil {
+					r.log.Error(err, "record revision outcome error")
+				}
+
+				if r.config.Rollback.ShouldRollback(revision) {
+					if err := r.rollback(revision); err != nil {
+						r.log.Error(err, "rollback error")
+					}
+					return
+				}
+			}
+
+			// The original revision is not restored while the function is frozen.
+			if r.config.Rollback != nil && r.config.Freeze == nil {
+				inCooldown, err := r.checkRollback()
+				if err != nil {
+					r.log.Error(err, "check rollback error")
+					return
+				}
+
+				if inCooldown {
+					r.log.V(1).Info("function was rolled back, wait for the cooldown")
+					return
+				}
+			}
+
+			image := r.config.image
+			if r.config.WaitForBuild {
+				function, err := r.getFunction()
+				if err != nil {
+					r.log.Error(err, "get function error")
+					return
+				}
+
+				if rollout.BuildInProgress(function) {
+					r.log.V(1).Info("function is being rebuilt, wait for the build")
+					r.rebuilding = true
+					return
+				}
+			}
+
+			if r.config.tagPolicy != nil {
+				var err error
+				image, err = r.getLatestImage()
+				if err != nil {
+					r.handleError(err, "get latest image tag error")
+					return
+				}
+			}
+
+			digests, err := r.getLatestImageDigests(image)
 			if err != nil {
-				r.log.Error(err, "get image digest error")
+				r.handleError(err, "get image digest error")
 				return
 			}
+			r.lastErrorClass = errorClassNone
+			r.rateLimitBackoff = 0
+
+			if err := r.recordDigests(digests); err != nil {
+				r.log.Error(err, "record image digests error")
+			}
 
 			currentDigest, err := r.getCurrentImageDigest()
-			if currentDigest == digest {
+			if err != nil {
+				r.log.Error(err, "get current image digest error")
+				return
+			}
+
+			rebuilt := r.rebuilding
+			r.rebuilding = false
+			if image == r.config.image && currentDigest == digests.digest() {
 				r.log.V(1).Info("image has no change")
 				return
 			}
 
-			if err := r.updateFunctionStatus(digest); err != nil {
-				r.log.Error(err, "update function status error")
+			// The image pushed by the build is deployed by the build, record it only.
+			if rebuilt && image == r.config.image {
+				if err := r.recordBaseline(digests.digest()); err != nil {
+					r.log.Error(err, "record rebuilt revision error")
+					return
+				}
+
+				r.log.Info("function was rebuilt, record the rebuilt revision", "revision", digests.digest())
+				r.recordRevision(image, digests.digest(), rollout.ReasonRebuild)
+				return
+			}
+
+			if currentDigest == "" && image == r.config.image {
+				switch r.config.InitialRevision {
+				case constants.InitialRevisionWait:
+					r.log.V(1).Info("function was just created, wait for the first deployment")
+					return
+				case constants.InitialRevisionBaseline:
+					if err := r.recordBaseline(digests.digest()); err != nil {
+						r.log.Error(err, "record baseline revision error")
+						return
+					}
+
+					r.log.Info("function was just created, record the baseline revision", "revision", digests.digest())
+					r.recordRevision(image, digests.digest(), rollout.ReasonBaseline)
+					return
+				}
+			}
+
+			if reason := r.config.Freeze.Blocks(digests.digest()); reason != "" {
+				if err := r.setPending(digests.digest(), fmt.Sprintf("update available: revision %s, not applied because %s", digests.digest(), reason)); err != nil {
+					r.log.Error(err, "record pending revision error")
+				}
+
+				r.log.V(1).Info("update available, not applied", "revision", digests.digest(), "reason", reason)
 				return
 			}
 
+			if r.verifier != nil {
+				if err := r.verifyDigests(image, digests); err != nil {
+					if r.rejectedDigest != digests.digest() {
+						r.log.Error(err, "image verification failed, refuse to update function")
+						r.recorder.Event(r.fn, v1.EventTypeWarning, "ImageVerificationFailed", err.Error())
+						r.rejectedDigest = digests.digest()
+					}
+					return
+				}
+			}
+
+			if r.config.ManualApproval {
+				approved, err := r.approved(digests.digest())
+				if err != nil {
+					r.log.Error(err, "check approval error")
+					return
+				}
+
+				if !approved {
+					r.log.V(1).Info("image changed, wait for approval", "image", image, "revision", digests.digest())
+					return
+				}
+			}
+
+			if !r.config.Schedule.Allowed(time.Now()) {
+				if err := r.setPending(digests.digest(), fmt.Sprintf("image changed to %s@%s, the redeploy is queued until the next maintenance window", image, digests.digest())); err != nil {
+					r.log.Error(err, "record pending revision error")
+				}
+
+				r.log.V(1).Info("image changed, queued until the next maintenance window", "image", image, "revision", digests.digest())
+				return
+			}
+
+			if image != r.config.image {
+				r.log.Info("new image tag found, update function", "image", image)
+			}
+
+			specImage := image
+			if r.config.pinDigest {
+				specImage = pinDigest(image, digests.digest())
+			}
+
+			if err := r.updateFunctionImage(specImage); err != nil {
+				r.log.Error(err, "update function image error")
+				return
+			}
+			r.config.image = image
+			r.setWatched()
+
+			if currentDigest != digests.digest() {
+				if err := r.updateFunctionStatus(digests.digest()); err != nil {
+					r.log.Error(err, "update function status error")
+					return
+				}
+			}
+
+			if err := r.completeApproval(); err != nil {
+				r.log.Error(err, "clear approval error")
+			}
+
+			reason := rollout.ReasonChanged
+			if currentDigest == "" {
+				reason = rollout.ReasonInitial
+			} else if r.config.ManualApproval {
+				reason = rollout.ReasonApproved
+			} else if currentDigest == digests.digest() {
+				reason = rollout.ReasonNewTag
+			}
+			r.recordRevision(image, digests.digest(), reason)
+
 			return
 		}
 
 		for {
+			r.lock.Lock()
+			compare()
+			interval := r.config.PollingInterval
+			r.lock.Unlock()
+
 			select {
 			case <-r.stopCh:
 				r.log.Info("revision controller stopped")
 				return
-			default:
+			case <-r.triggerCh:
+				r.log.V(1).Info("revision controller triggered")
+			case <-time.After(interval):
 			}
-
-			compare()
-			time.Sleep(r.config.PollingInterval)
 		}
 	}()
 
@@ -107,17 +359,35 @@ func (r *RevisionController) Start() {
 }
 
 func (r *RevisionController) Update(config map[string]string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.update(config)
+}
+
+// update applies the params, the lock must be held by the caller.
+func (r *RevisionController) update(config map[string]string) error {
 	revisionControllerConfig, err := r.getRevisionControllerConfig(config)
 	if err != nil {
 		return err
 	}
 
-	r.keychain, err = r.getKeychain(revisionControllerConfig)
+	keychain, cred, err := r.getKeychain(revisionControllerConfig, config)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := r.getVerifier(config)
 	if err != nil {
 		return err
 	}
 
+	r.keychain = keychain
+	r.verifier = verifier
+	r.credential = cred
+	r.params = config
 	r.config = revisionControllerConfig
+	r.setWatched()
 	return nil
 }
 
@@ -126,6 +396,65 @@ func (r *RevisionController) Stop() {
 	signal.Stop(r.stopCh)
 }
 
+func (r *RevisionController) Trigger() {
+	select {
+	case r.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// setWatched takes the snapshot of the image watched, the lock must be held by the caller.
+func (r *RevisionController) setWatched() {
+	var watched *watchedImage
+	if ref, err := r.parseReference(r.config.image); err == nil {
+		if refs, err := r.mirrorReferences(ref); err == nil {
+			watched = &watchedImage{ref: ref, refs: refs, tagPolicy: r.config.tagPolicy}
+		}
+	}
+
+	r.watchedLock.Lock()
+	defer r.watchedLock.Unlock()
+	r.watched = watched
+}
+
+// WatchesImage returns true if the repository is the repository of the image or one of its mirrors,
+// and the tag is the tag of the image or matches the tag policy.
+func (r *RevisionController) WatchesImage(repository string, tag string) bool {
+	r.watchedLock.RLock()
+	watched := r.watched
+	r.watchedLock.RUnlock()
+	if watched == nil {
+		return false
+	}
+
+	ref := watched.ref
+	found := false
+	for _, item := range watched.refs {
+		if item.Context().Name() == repository {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return false
+	}
+
+	if tag == "" {
+		return true
+	}
+
+	if watched.tagPolicy != nil {
+		return watched.tagPolicy.latest([]string{tag}) != ""
+	}
+
+	if t, ok := ref.(name.Tag); ok {
+		return t.TagStr() == tag
+	}
+
+	return false
+}
+
 func (r *RevisionController) getRevisionControllerConfig(config map[string]string) (*Config, error) {
 	function, err := r.getFunction()
 	if err != nil {
@@ -151,62 +480,117 @@ func (r *RevisionController) getRevisionControllerConfig(config map[string]strin
 	revisionControllerConfig := &Config{
 		RevisionControllerType: config[constants.RevisionControllerType],
 		PollingInterval:        interval,
+		ManualApproval:         rollout.IsManualApproval(config),
+		Freeze:                 rollout.NewFreeze(config),
+		WaitForBuild:           config[constants.WaitForBuild] == "true",
 		imageConfig: imageConfig{
-			insecure:   insecure,
-			credential: function.Spec.ImageCredentials,
+			insecure:       insecure,
+			credential:     function.Spec.ImageCredentials,
+			pullSecrets:    utils.SplitList(config[constants.ImagePullSecrets]),
+			cloudKeychains: utils.SplitList(config[constants.CloudKeychains]),
 		},
 	}
 
+	revisionControllerConfig.serviceAccount = ServiceAccountName(function, config)
+
+	revisionControllerConfig.platform, err = parsePlatform(config[constants.Platform])
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.tagPolicy, err = newTagPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.InitialRevision, err = rollout.GetInitialRevisionPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.HistoryLimit, err = rollout.GetHistoryLimit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionControllerConfig.Rollback, err = rollout.NewRollbackPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if revisionControllerConfig.Rollback != nil && revisionControllerConfig.HistoryLimit <= 0 {
+		return nil, fmt.Errorf("%s", "the revision history is required by the automatic rollback")
+	}
+
+	revisionControllerConfig.Schedule, err = rollout.NewSchedule(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cloud := range revisionControllerConfig.cloudKeychains {
+		if _, ok := cloudRegistries[cloud]; !ok {
+			return nil, fmt.Errorf("unspport cloud keychain, %s", cloud)
+		}
+	}
+
 	if revisionControllerConfig.RevisionControllerType == constants.RevisionControllerTypeImage {
 		revisionControllerConfig.image = function.Spec.Image
+		revisionControllerConfig.pinDigest = config[constants.PinDigest] == "true"
 	} else if revisionControllerConfig.RevisionControllerType == constants.RevisionControllerTypeSourceImage {
 		revisionControllerConfig.image = function.Spec.Build.SrcRepo.BundleContainer.Image
 	}
 
-	return revisionControllerConfig, nil
-}
-
-func (r *RevisionController) getKeychain(revisionControllerConfig *Config) (authn.Keychain, error) {
-	if revisionControllerConfig.credential == nil {
-		return nil, fmt.Errorf("image credential must be specified")
+	// The digest pinned by the revision controller or by the rollback is dropped, so that the tag is watched.
+	rollback, _ := rollout.GetRollback(function, revisionControllerConfig.RevisionControllerType)
+	if revisionControllerConfig.pinDigest || rollback != nil {
+		revisionControllerConfig.image = unpinDigest(revisionControllerConfig.image)
 	}
 
-	secret := v1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      revisionControllerConfig.credential.Name,
-			Namespace: r.fn.Namespace,
-		},
+	revisionControllerConfig.mirrors, err = getMirrors(config)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := r.Get(context.Background(), client.ObjectKeyFromObject(&secret), &secret); err != nil {
+	revisionControllerConfig.transport, err = r.getTransport(revisionControllerConfig, config)
+	if err != nil {
 		return nil, err
 	}
 
-	return k8schain.NewFromPullSecrets(context.Background(), []v1.Secret{secret})
+	return revisionControllerConfig, nil
 }
 
-func (r *RevisionController) getLatestImageDigest() (string, error) {
-	var auth authn.Authenticator
-	opts := []name.Option{name.WeakValidation}
-	if r.config.insecure {
-		opts = append(opts, name.Insecure)
-	}
-	ref, err := name.ParseReference(r.config.image, opts...)
+// getLatestImageDigests resolves the digests of the image from the mirrors of its registry first,
+// the registry of the image is used if all the mirrors fail.
+func (r *RevisionController) getLatestImageDigests(image string) (*imageDigests, error) {
+	ref, err := r.parseReference(image)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	auth, err = r.keychain.Resolve(ref.Context().Registry)
+	refs, err := r.mirrorReferences(ref)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	descriptor, err := remote.Head(ref, remote.WithAuth(auth))
-	if err != nil {
-		return "", err
+	var digests *imageDigests
+	for _, ref := range refs {
+		var auth authn.Authenticator
+		auth, err = r.keychain.Resolve(ref.Context().Registry)
+		if err != nil {
+			return nil, err
+		}
+
+		digests, err = r.resolveDigests(ref, auth)
+		if err == nil {
+			return digests, nil
+		}
+
+		if ref.Context().Registry != refs[len(refs)-1].Context().Registry {
+			r.log.V(1).Info("get image digest from mirror error, try next", "mirror", ref.Context().RegistryStr(), "error", err.Error())
+		}
 	}
 
-	return descriptor.Digest.String(), nil
+	return nil, err
 }
 
 func (r *RevisionController) getCurrentImageDigest() (string, error) {
@@ -223,7 +607,7 @@ func (r *RevisionController) getCurrentImageDigest() (string, error) {
 		return function.Status.Revision.ImageDigest, nil
 	} else if r.config.RevisionControllerType == constants.RevisionControllerTypeSourceImage {
 		for _, source := range function.Status.Sources {
-			if source.Name == "default" && source.Bundle != nil {
+			if source.Name == constants.DefaultSourceName && source.Bundle != nil {
 				return source.Bundle.Digest, nil
 			}
 		}
@@ -232,35 +616,104 @@ func (r *RevisionController) getCurrentImageDigest() (string, error) {
 	return "", nil
 }
 
+// recordBaseline records the digest as the revision of the function without redeploying or rebuilding it.
+func (r *RevisionController) recordBaseline(digest string) error {
+	return rollout.PatchStatus(r.Client, r.fn, func(function *openfunction.Function) {
+		switch r.config.RevisionControllerType {
+		case constants.RevisionControllerTypeImage:
+			function.Status.Revision = &openfunction.Revision{ImageDigest: digest}
+		case constants.RevisionControllerTypeSourceImage:
+			rollout.SetSource(function, bundleSource(digest))
+		}
+	})
+}
+
 func (r *RevisionController) updateFunctionStatus(digest string) error {
-	function, err := r.getFunction()
+	err := rollout.PatchStatus(r.Client, r.fn, func(function *openfunction.Function) {
+		switch r.config.RevisionControllerType {
+		case constants.RevisionControllerTypeImage:
+			if function.Status.Serving == nil {
+				function.Status.Serving = &openfunction.Condition{}
+			}
+			function.Status.Serving.State = ""
+			function.Status.Serving.ResourceHash = ""
+			function.Status.Revision = &openfunction.Revision{ImageDigest: digest}
+		case constants.RevisionControllerTypeSourceImage:
+			function.Status.Build = nil
+			rollout.SetSource(function, bundleSource(digest))
+		}
+	})
 	if err != nil {
 		return err
 	}
 
 	switch r.config.RevisionControllerType {
 	case constants.RevisionControllerTypeImage:
-		if function.Status.Serving == nil {
-			function.Status.Serving = &openfunction.Condition{}
-		}
-		function.Status.Serving.State = ""
-		function.Status.Serving.ResourceHash = ""
-		function.Status.Revision = &openfunction.Revision{ImageDigest: digest}
 		r.log.Info("image changed, rerun serving")
 	case constants.RevisionControllerTypeSourceImage:
-		function.Status.Build = nil
-		function.Status.Sources = nil
-		function.Status.Sources = append(function.Status.Sources, openfunction.SourceResult{
-			Name: "default",
-			Bundle: &openfunction.BundleSourceResult{
-				Digest: digest,
-			},
-		})
-
 		r.log.Info("source image changed, rebuild function")
 	}
 
-	return r.Status().Update(context.Background(), function)
+	return nil
+}
+
+// recordRevision records the image digest applied in the revision history of the function.
+func (r *RevisionController) recordRevision(image string, digest string, reason string) {
+	revision := rollout.Revision{
+		Type:     r.config.RevisionControllerType,
+		Revision: digest,
+		Image:    image,
+		Reason:   reason,
+	}
+	if err := rollout.RecordRevision(r.Client, r.fn, revision, r.config.HistoryLimit); err != nil {
+		r.log.Error(err, "record revision history error")
+	}
+}
+
+// outcome returns the outcome of the revision, it's the state of the serving for the image revision controller,
+// and the state of the build for the source image revision controller.
+func (r *RevisionController) outcome(function *openfunction.Function) string {
+	if r.config.RevisionControllerType == constants.RevisionControllerTypeImage {
+		return rollout.ServingOutcome(function)
+	}
+
+	return rollout.BuildOutcome(function)
+}
+
+func bundleSource(digest string) openfunction.SourceResult {
+	return openfunction.SourceResult{
+		Name: constants.DefaultSourceName,
+		Bundle: &openfunction.BundleSourceResult{
+			Digest: digest,
+		},
+	}
+}
+
+func (r *RevisionController) approved(digest string) (bool, error) {
+	function, err := r.getFunction()
+	if err != nil {
+		return false, err
+	}
+
+	return rollout.Approved(r.Client, r.recorder, function, r.config.RevisionControllerType, digest)
+}
+
+func (r *RevisionController) setPending(revision string, message string) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	return rollout.SetPending(r.Client, r.recorder, function, r.config.RevisionControllerType, revision, message)
+}
+
+func (r *RevisionController) completeApproval() error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	return rollout.Complete(r.Client, function, r.config.RevisionControllerType)
 }
 
 func (r *RevisionController) getFunction() (*openfunction.Function, error) {
@@ -0,0 +1,278 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	"github.com/google/go-containerregistry/pkg/name"
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/credential"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	credentialprovider "github.com/vdemeester/k8s-pkg-credentialprovider"
+	credentialprovidersecrets "github.com/vdemeester/k8s-pkg-credentialprovider/secrets"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	username = "username"
+	password = "password"
+	token    = "token"
+
+	defaultServiceAccount = "default"
+
+	cloudECR = "ecr"
+	cloudGCR = "gcr"
+	cloudACR = "acr"
+)
+
+var (
+	// cloudRegistries are the registry host suffixes served by the cloud keychains.
+	cloudRegistries = map[string][]string{
+		cloudECR: {".amazonaws.com", ".amazonaws.com.cn"},
+		cloudGCR: {"gcr.io", "-docker.pkg.dev"},
+		cloudACR: {".azurecr.io", ".azurecr.cn", ".azurecr.de", ".azurecr.us"},
+	}
+
+	cloudKeychainOnce sync.Once
+	cloudKeychainBase authn.Keychain
+	cloudKeychainErr  error
+)
+
+// staticKeychain resolves every registry to the same authenticator.
+type staticKeychain struct {
+	auth authn.Authenticator
+}
+
+func (k *staticKeychain) Resolve(_ authn.Resource) (authn.Authenticator, error) {
+	return k.auth, nil
+}
+
+// dockerKeychain resolves the registries with a docker keyring built from the pull secrets,
+// the docker config of the node is used as the fallback.
+type dockerKeychain struct {
+	keyring credentialprovider.DockerKeyring
+}
+
+func (k *dockerKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	image := target.RegistryStr() + "/foo/bar"
+	if repo, ok := target.(name.Repository); ok {
+		image = repo.String()
+	}
+
+	creds, found := k.keyring.Lookup(image)
+	if !found || len(creds) < 1 {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      creds[0].Username,
+		Password:      creds[0].Password,
+		Auth:          creds[0].Auth,
+		IdentityToken: creds[0].IdentityToken,
+		RegistryToken: creds[0].RegistryToken,
+	}), nil
+}
+
+// cloudKeychain resolves the registries of the enabled clouds with the credential helpers of k8schain,
+// the other registries are resolved to anonymous.
+type cloudKeychain struct {
+	keychain authn.Keychain
+	clouds   []string
+}
+
+func (k *cloudKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	registry := target.RegistryStr()
+	for _, cloud := range k.clouds {
+		for _, suffix := range cloudRegistries[cloud] {
+			if strings.HasSuffix(registry, suffix) {
+				return k.keychain.Resolve(target)
+			}
+		}
+	}
+
+	return authn.Anonymous, nil
+}
+
+// getKeychain builds the keychain used to access the image registry, in order of precedence:
+// the function credential, the `image-pull-secrets`, the image pull secrets of the service account,
+// the docker config of the node and the controller, and the cloud keychains when enabled.
+func (r *RevisionController) getKeychain(revisionControllerConfig *Config, config map[string]string) (authn.Keychain, *credential.Credential, error) {
+	if config[constants.Anonymous] == "true" {
+		r.setAnonymous(true)
+		return &staticKeychain{auth: authn.Anonymous}, nil, nil
+	}
+
+	var cred *credential.Credential
+	var keychains []authn.Keychain
+	source, err := credential.NewSource(r.Client, r.clientset, r.fn.Namespace, revisionControllerConfig.credential, config)
+	if err != nil && !errors.Is(err, credential.ErrNotSpecified) {
+		return nil, nil, err
+	}
+
+	if source != nil {
+		cred, err = credential.Refresh(context.Background(), source, r.credential)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keychain, err := newKeychain(cred)
+		if err != nil {
+			return nil, nil, err
+		}
+		keychains = append(keychains, keychain)
+	}
+
+	pullSecrets, err := r.getPullSecrets(revisionControllerConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fall back to anonymous access for public images if no credential is found.
+	r.setAnonymous(cred == nil && len(pullSecrets) == 0)
+
+	keyring, err := credentialprovidersecrets.MakeDockerKeyring(pullSecrets, nodeKeyring())
+	if err != nil {
+		return nil, nil, err
+	}
+	keychains = append(keychains, &dockerKeychain{keyring: keyring}, authn.DefaultKeychain)
+
+	if len(revisionControllerConfig.cloudKeychains) > 0 {
+		cloudKeychainOnce.Do(func() {
+			cloudKeychainBase, cloudKeychainErr = k8schain.NewNoClient(context.Background())
+		})
+		if cloudKeychainErr != nil {
+			return nil, nil, cloudKeychainErr
+		}
+
+		keychains = append(keychains, &cloudKeychain{
+			keychain: cloudKeychainBase,
+			clouds:   revisionControllerConfig.cloudKeychains,
+		})
+	}
+
+	return authn.NewMultiKeychain(keychains...), cred, nil
+}
+
+// ServiceAccountName returns the service account whose image pull secrets are used, it's the `service-account` param,
+// or the service account of the serving for the image revision controller, or the default service account.
+func ServiceAccountName(function *openfunction.Function, config map[string]string) string {
+	if config[constants.ServiceAccount] != "" {
+		return config[constants.ServiceAccount]
+	}
+
+	if utils.StringInList(constants.RevisionControllerTypeImage, utils.SplitList(config[constants.RevisionControllerType])) &&
+		function.Spec.Serving != nil &&
+		function.Spec.Serving.Template != nil &&
+		function.Spec.Serving.Template.ServiceAccountName != "" {
+		return function.Spec.Serving.Template.ServiceAccountName
+	}
+
+	return defaultServiceAccount
+}
+
+// getPullSecrets returns the secrets specified by `image-pull-secrets` and the image pull secrets of
+// the service account, the secrets not found are ignored like what the kubelet does.
+func (r *RevisionController) getPullSecrets(revisionControllerConfig *Config) ([]v1.Secret, error) {
+	names := append([]string{}, revisionControllerConfig.pullSecrets...)
+
+	sa := &v1.ServiceAccount{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: r.fn.Namespace, Name: revisionControllerConfig.serviceAccount}, sa); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		r.log.V(1).Info("service account not found", "ServiceAccount", revisionControllerConfig.serviceAccount)
+	}
+
+	for _, ref := range sa.ImagePullSecrets {
+		names = append(names, ref.Name)
+	}
+
+	var secrets []v1.Secret
+	for _, n := range names {
+		secret := v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      n,
+				Namespace: r.fn.Namespace,
+			},
+		}
+
+		if err := r.Get(context.Background(), client.ObjectKeyFromObject(&secret), &secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			r.log.Info("image pull secret not found", "Secret", n)
+			continue
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+func (r *RevisionController) setAnonymous(anonymous bool) {
+	if anonymous && !r.anonymous {
+		r.log.Info("no image credential found, access the image registry anonymously, "+
+			"the registry may be rate limited, consider setting a credential or increasing the polling interval",
+			"pollingInterval", r.config.PollingInterval)
+	}
+
+	r.anonymous = anonymous
+}
+
+// nodeKeyring returns the keyring built from the docker config of the node if it's mounted.
+func nodeKeyring() credentialprovider.DockerKeyring {
+	keyring := &credentialprovider.BasicDockerKeyring{}
+	if cfg, err := credentialprovider.ReadDockerConfigFile(); err == nil {
+		keyring.Add(cfg)
+	}
+
+	return keyring
+}
+
+// newKeychain builds a keychain from the credential, docker config credentials are resolved per registry,
+// while username/password and token credentials are used for all registries.
+func newKeychain(cred *credential.Credential) (authn.Keychain, error) {
+	_, hasDockerConfigJson := cred.Data[v1.DockerConfigJsonKey]
+	_, hasDockerConfig := cred.Data[v1.DockerConfigKey]
+	if hasDockerConfigJson || hasDockerConfig {
+		secret := v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: cred.Name,
+			},
+			Type: cred.Type,
+			Data: cred.Data,
+		}
+
+		keyring, err := credentialprovidersecrets.MakeDockerKeyring([]v1.Secret{secret}, &credentialprovider.BasicDockerKeyring{})
+		if err != nil {
+			return nil, err
+		}
+
+		return &dockerKeychain{keyring: keyring}, nil
+	}
+
+	if data, ok := cred.Data[password]; ok {
+		return &staticKeychain{auth: authn.FromConfig(authn.AuthConfig{
+			Username: string(cred.Data[username]),
+			Password: string(data),
+		})}, nil
+	}
+
+	if data, ok := cred.Data[token]; ok {
+		return &staticKeychain{auth: authn.FromConfig(authn.AuthConfig{
+			RegistryToken: string(data),
+		})}, nil
+	}
+
+	return nil, fmt.Errorf("no usable key found in credential %s", cred.Name)
+}
@@ -0,0 +1,129 @@
+package image
+
+import (
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+	"github.com/openfunction/revision-controller/pkg/utils"
+)
+
+const (
+	// platformIndex means the digest of the image index is watched explicitly.
+	platformIndex = "index"
+)
+
+// imageDigests holds the digests resolved for an image.
+type imageDigests struct {
+	// Index is the digest of the image index, it's empty if the image is not a multi-arch image.
+	Index string `yaml:"index,omitempty"`
+	// Platform is the platform resolved from the image index.
+	Platform string `yaml:"platform,omitempty"`
+	// Manifest is the digest of the image manifest of the platform,
+	// it's empty if the image is a multi-arch image and no platform is specified.
+	Manifest string `yaml:"manifest,omitempty"`
+}
+
+// digest returns the digest watched by the revision controller.
+func (d *imageDigests) digest() string {
+	if d.Manifest != "" {
+		return d.Manifest
+	}
+
+	return d.Index
+}
+
+// resolveDigests resolves the digests of the image, if a platform is specified, the image manifest of
+// the platform is resolved from the image index, so that the changes of other platforms are ignored.
+func (r *RevisionController) resolveDigests(ref name.Reference, auth authn.Authenticator) (*imageDigests, error) {
+	if r.config.platform == nil {
+		descriptor, err := r.head(ref, auth)
+		if err != nil {
+			return nil, err
+		}
+
+		if descriptor.MediaType.IsIndex() {
+			return &imageDigests{Index: descriptor.Digest.String()}, nil
+		}
+
+		return &imageDigests{Manifest: descriptor.Digest.String()}, nil
+	}
+
+	descriptor, err := remote.Get(ref, r.remoteOptions(remote.WithAuth(auth), remote.WithPlatform(*r.config.platform))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !descriptor.MediaType.IsIndex() {
+		return &imageDigests{Manifest: descriptor.Digest.String()}, nil
+	}
+
+	img, err := descriptor.Image()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	return &imageDigests{
+		Index:    descriptor.Digest.String(),
+		Platform: r.config.platform.String(),
+		Manifest: digest.String(),
+	}, nil
+}
+
+// head resolves the descriptor of the image with HEAD, and falls back to GET if the registry does not
+// support it, the digest is computed from the manifest in this case. The registry is remembered,
+// so that HEAD is not tried again.
+func (r *RevisionController) head(ref name.Reference, auth authn.Authenticator) (*gcrv1.Descriptor, error) {
+	registry := ref.Context().RegistryStr()
+	if !r.headUnsupported[registry] {
+		descriptor, err := remote.Head(ref, r.remoteOptions(remote.WithAuth(auth))...)
+		if err == nil || !isHeadUnsupported(err) {
+			return descriptor, err
+		}
+
+		r.log.Info("image registry does not support resolving digest with HEAD, fall back to GET",
+			"registry", registry, "error", err.Error())
+		r.headUnsupported[registry] = true
+	}
+
+	descriptor, err := remote.Get(ref, r.remoteOptions(remote.WithAuth(auth))...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &descriptor.Descriptor, nil
+}
+
+// recordDigests records all the digests resolved for the image in the annotation of the function,
+// the annotation is a yaml map keyed by the revision controller types, since both the image and the
+// source image revision controllers record their digests.
+func (r *RevisionController) recordDigests(digests *imageDigests) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	if data := function.Annotations[constants.ImageDigestsAnnotation]; data != "" {
+		recorded := make(map[string]imageDigests)
+		if err := utils.YamlUnmarshal([]byte(data), recorded); err == nil && recorded[r.config.RevisionControllerType] == *digests {
+			return nil
+		}
+	}
+
+	return rollout.PatchTypedAnnotation(r.Client, function, constants.ImageDigestsAnnotation, r.config.RevisionControllerType, digests)
+}
+
+func parsePlatform(str string) (*gcrv1.Platform, error) {
+	if str == "" || str == platformIndex {
+		return nil, nil
+	}
+
+	return gcrv1.ParsePlatform(str)
+}
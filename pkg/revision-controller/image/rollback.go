@@ -0,0 +1,119 @@
+package image
+
+import (
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/revision-controller/rollout"
+	v1 "k8s.io/api/core/v1"
+)
+
+// rollback pins the image of the function to the last known-good image digest, in the form of `repo:tag@digest`,
+// the original image is kept in the rollback state, so that it can be restored after the cooldown.
+// The new digests are not applied until the cooldown ends.
+func (r *RevisionController) rollback(failed *rollout.Revision) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	good, err := rollout.LastGood(function, r.config.RevisionControllerType, failed.Revision)
+	if err != nil {
+		return err
+	}
+
+	if good == nil {
+		r.log.Info("the new revision failed, no known-good revision to roll back to", "revision", failed.Revision)
+		r.recorder.Eventf(function, v1.EventTypeWarning, "RollbackFailed",
+			"revision %s failed, no known-good revision to roll back to", failed.Revision)
+		return nil
+	}
+
+	if reason := r.config.Freeze.Blocks(good.Revision); reason != "" {
+		r.log.Info("the new revision failed, not rolled back", "revision", failed.Revision, "reason", reason)
+		r.recorder.Eventf(function, v1.EventTypeWarning, "RollbackBlocked",
+			"revision %s failed, not rolled back to %s because %s", failed.Revision, good.Revision, reason)
+		return nil
+	}
+
+	state := r.config.Rollback.NewState(good, failed)
+	original := r.specImage(function)
+	state.Original = &original
+	if current, err := rollout.GetRollback(function, r.config.RevisionControllerType); err == nil && current != nil {
+		state.Original = current.Original
+	}
+
+	if err := rollout.SetRollback(r.Client, r.fn, r.config.RevisionControllerType, state); err != nil {
+		return err
+	}
+
+	image := r.config.image
+	if good.Image != "" {
+		image = good.Image
+	}
+
+	// The digest is always pinned, otherwise the tag still points to the failed digest.
+	if err := r.updateFunctionImage(pinDigest(image, good.Revision)); err != nil {
+		return err
+	}
+	r.config.image = image
+	r.setWatched()
+
+	if err := r.updateFunctionStatus(good.Revision); err != nil {
+		return err
+	}
+
+	r.log.Info("the new revision failed, roll back to the known-good revision",
+		"failed", failed.Revision, "revision", good.Revision, "until", state.Until)
+	r.recorder.Eventf(function, v1.EventTypeWarning, "RevisionRolledBack",
+		"revision %s %s, rolled back to %s@%s, the new revision will be retried after %s",
+		failed.Revision, failed.Outcome, image, good.Revision, state.Until)
+	r.recordRevision(image, good.Revision, rollout.ReasonRollback)
+	return nil
+}
+
+// checkRollback returns true if the function is rolled back and the cooldown is not over,
+// after the cooldown, the original image is restored so that the new revision is retried.
+func (r *RevisionController) checkRollback() (bool, error) {
+	function, err := r.getFunction()
+	if err != nil {
+		return false, err
+	}
+
+	state, err := rollout.GetRollback(function, r.config.RevisionControllerType)
+	if err != nil || state == nil {
+		return false, err
+	}
+
+	if state.InCooldown() {
+		return true, nil
+	}
+
+	if state.Original != nil {
+		if err := r.updateFunctionImage(*state.Original); err != nil {
+			return false, err
+		}
+
+		image := *state.Original
+		if r.config.pinDigest {
+			image = unpinDigest(image)
+		}
+		r.config.image = image
+		r.setWatched()
+	}
+
+	if err := rollout.SetRollback(r.Client, r.fn, r.config.RevisionControllerType, nil); err != nil {
+		return false, err
+	}
+
+	r.log.Info("rollback cooldown is over, retry the new revision", "failed", state.Failed)
+	return false, nil
+}
+
+// specImage returns the image of the function watched by the revision controller.
+func (r *RevisionController) specImage(function *openfunction.Function) string {
+	if r.config.RevisionControllerType == constants.RevisionControllerTypeSourceImage {
+		return function.Spec.Build.SrcRepo.BundleContainer.Image
+	}
+
+	return function.Spec.Image
+}
@@ -0,0 +1,219 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	tagOrderSemver       = "semver"
+	tagOrderAlphabetical = "alphabetical"
+	tagOrderNumerical    = "numerical"
+)
+
+// tagPolicy selects the newest tag of the image repository.
+// The tags are filtered by the `tag-pattern` regex and the `semver-range` constraint, and ordered by
+// the `tag-order`. If the pattern has a capture group, the first group is used to order the tags.
+type tagPolicy struct {
+	pattern     *regexp.Regexp
+	semverRange *semver.Constraints
+	order       string
+}
+
+func newTagPolicy(config map[string]string) (*tagPolicy, error) {
+	if config[constants.TagPattern] == "" && config[constants.SemverRange] == "" {
+		return nil, nil
+	}
+
+	p := &tagPolicy{
+		order: config[constants.TagOrder],
+	}
+
+	if str := config[constants.TagPattern]; str != "" {
+		var err error
+		p.pattern, err = regexp.Compile(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if str := config[constants.SemverRange]; str != "" {
+		var err error
+		p.semverRange, err = semver.NewConstraint(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.order == "" {
+		p.order = tagOrderSemver
+		if p.semverRange == nil {
+			p.order = tagOrderAlphabetical
+		}
+	}
+
+	switch p.order {
+	case tagOrderSemver, tagOrderAlphabetical, tagOrderNumerical:
+	default:
+		return nil, fmt.Errorf("unspport tag order, %s", p.order)
+	}
+
+	return p, nil
+}
+
+// latest returns the newest tag matching the policy, or empty if no tag matches.
+func (p *tagPolicy) latest(tags []string) string {
+	latest, latestKey := "", ""
+	var latestVersion *semver.Version
+	var latestNumber float64
+	for _, tag := range tags {
+		key := tag
+		if p.pattern != nil {
+			matches := p.pattern.FindStringSubmatch(tag)
+			if matches == nil {
+				continue
+			}
+
+			if len(matches) > 1 {
+				key = matches[1]
+			}
+		}
+
+		switch p.order {
+		case tagOrderSemver:
+			version, err := semver.NewVersion(key)
+			if err != nil {
+				continue
+			}
+
+			if p.semverRange != nil && !p.semverRange.Check(version) {
+				continue
+			}
+
+			if latestVersion == nil || version.GreaterThan(latestVersion) {
+				latest, latestVersion = tag, version
+			}
+		case tagOrderNumerical:
+			number, err := strconv.ParseFloat(key, 64)
+			if err != nil {
+				continue
+			}
+
+			if latest == "" || number > latestNumber {
+				latest, latestNumber = tag, number
+			}
+		case tagOrderAlphabetical:
+			if latest == "" || key > latestKey {
+				latest, latestKey = tag, key
+			}
+		}
+	}
+
+	return latest
+}
+
+// getLatestImage lists the tags of the image repository and returns the image with the newest tag.
+func (r *RevisionController) getLatestImage() (string, error) {
+	ref, err := r.parseReference(r.config.image)
+	if err != nil {
+		return "", err
+	}
+
+	tags, err := remote.List(ref.Context(), r.remoteOptions(remote.WithAuthFromKeychain(r.keychain))...)
+	if err != nil {
+		return "", err
+	}
+
+	tag := r.config.tagPolicy.latest(tags)
+	if tag == "" {
+		return "", fmt.Errorf("no tag of %s matches the tag policy", ref.Context().String())
+	}
+
+	return replaceTag(r.config.image, tag), nil
+}
+
+// updateFunctionImage points the function to the image with the new tag or the pinned digest.
+func (r *RevisionController) updateFunctionImage(image string) error {
+	function, err := r.getFunction()
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(function.DeepCopy())
+	switch r.config.RevisionControllerType {
+	case constants.RevisionControllerTypeImage:
+		if function.Spec.Image == image {
+			return nil
+		}
+		function.Spec.Image = image
+	case constants.RevisionControllerTypeSourceImage:
+		if function.Spec.Build.SrcRepo.BundleContainer.Image == image {
+			return nil
+		}
+		function.Spec.Build.SrcRepo.BundleContainer.Image = image
+	}
+
+	return r.Patch(context.Background(), function, patch)
+}
+
+func (r *RevisionController) parseReference(image string) (name.Reference, error) {
+	opts := []name.Option{name.WeakValidation}
+	if r.config.insecure {
+		opts = append(opts, name.Insecure)
+	}
+
+	return name.ParseReference(image, opts...)
+}
+
+// replaceTag replaces the tag and the digest of the image with the tag,
+// the registry and the repository are kept as they are written.
+func replaceTag(image string, tag string) string {
+	if index := strings.Index(image, "@"); index >= 0 {
+		image = image[:index]
+	}
+
+	if index := strings.LastIndex(image, ":"); index > strings.LastIndex(image, "/") {
+		image = image[:index]
+	}
+
+	return image + ":" + tag
+}
+
+// pinDigest returns the image in the form of `repo:tag@digest`, the tag is kept so that it can be watched.
+func pinDigest(image string, digest string) string {
+	image = unpinDigest(image)
+	if index := strings.Index(image, "@"); index >= 0 {
+		image = image[:index]
+	}
+
+	if strings.LastIndex(image, ":") <= strings.LastIndex(image, "/") {
+		image = image + ":latest"
+	}
+
+	return image + "@" + digest
+}
+
+// unpinDigest drops the digest of the image in the form of `repo:tag@digest`,
+// the image without tag, `repo@digest`, is returned as it is.
+func unpinDigest(image string) string {
+	index := strings.Index(image, "@")
+	if index < 0 {
+		return image
+	}
+
+	repo := image[:index]
+	if strings.LastIndex(repo, ":") > strings.LastIndex(repo, "/") {
+		return repo
+	}
+
+	return image
+}
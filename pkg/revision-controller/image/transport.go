@@ -0,0 +1,224 @@
+package image
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	"golang.org/x/net/http/httpproxy"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	caFile         = "ca.crt"
+	clientCertFile = "client.cert"
+	clientKeyFile  = "client.key"
+)
+
+// registryTransport routes the requests to the transport of the registry,
+// the requests to other hosts, such as the token services, use the fallback transport.
+type registryTransport struct {
+	registries map[string]http.RoundTripper
+	fallback   http.RoundTripper
+}
+
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := t.registries[req.URL.Host]; ok {
+		return rt.RoundTrip(req)
+	}
+
+	return t.fallback.RoundTrip(req)
+}
+
+// getMirrors parses the `registry-mirrors` param, a comma separated list of `registry=mirror`,
+// a registry can have multiple mirrors which are tried in order.
+func getMirrors(config map[string]string) (map[string][]string, error) {
+	mirrors := make(map[string][]string)
+	for _, item := range utils.SplitList(config[constants.RegistryMirrors]) {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid registry mirror, %s", item)
+		}
+
+		registry, err := name.NewRegistry(kv[0], name.WeakValidation)
+		if err != nil {
+			return nil, err
+		}
+
+		mirrors[registry.RegistryStr()] = append(mirrors[registry.RegistryStr()], kv[1])
+	}
+
+	return mirrors, nil
+}
+
+// getTransport returns the transport used to access the registries of the image and its mirrors,
+// it's nil if no proxy or tls option is specified.
+// The CA bundles and the client certificates are read from the `certs-dir` which has the same layout
+// as `/etc/docker/certs.d`, and from the `tls-secret` which has the `ca.crt`, `tls.crt` and `tls.key` keys.
+func (r *RevisionController) getTransport(revisionControllerConfig *Config, config map[string]string) (http.RoundTripper, error) {
+	proxy := &httpproxy.Config{
+		HTTPProxy:  config[constants.HTTPProxy],
+		HTTPSProxy: config[constants.HTTPSProxy],
+		NoProxy:    config[constants.NoProxy],
+	}
+	certsDir := config[constants.CertsDir]
+	tlsSecret := config[constants.TLSSecret]
+	if proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" && certsDir == "" && tlsSecret == "" {
+		return nil, nil
+	}
+
+	base := remote.DefaultTransport.Clone()
+	if proxy.HTTPProxy != "" || proxy.HTTPSProxy != "" {
+		proxyFunc := proxy.ProxyFunc()
+		base.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	secret := &v1.Secret{}
+	if tlsSecret != "" {
+		if err := r.Get(context.Background(), client.ObjectKey{Namespace: r.fn.Namespace, Name: tlsSecret}, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []name.Option{name.WeakValidation}
+	if revisionControllerConfig.insecure {
+		opts = append(opts, name.Insecure)
+	}
+
+	ref, err := name.ParseReference(revisionControllerConfig.image, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	registries := []string{ref.Context().RegistryStr()}
+	for _, mirror := range revisionControllerConfig.mirrors[ref.Context().RegistryStr()] {
+		// The mirror may have a path prefix, only the host is used to match the requests.
+		registries = append(registries, strings.SplitN(mirror, "/", 2)[0])
+	}
+
+	t := &registryTransport{
+		registries: make(map[string]http.RoundTripper),
+		fallback:   base,
+	}
+	for _, registry := range registries {
+		tlsConfig, err := getTLSConfig(registry, certsDir, secret)
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig == nil {
+			continue
+		}
+
+		rt := base.Clone()
+		rt.TLSClientConfig = tlsConfig
+		t.registries[registry] = rt
+	}
+
+	return t, nil
+}
+
+func getTLSConfig(registry string, certsDir string, secret *v1.Secret) (*tls.Config, error) {
+	var cas [][]byte
+	var cert, key []byte
+	if certsDir != "" {
+		dir := filepath.Join(certsDir, registry)
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case entry.Name() == clientCertFile:
+				cert = data
+			case entry.Name() == clientKeyFile:
+				key = data
+			case strings.HasSuffix(entry.Name(), ".crt"):
+				cas = append(cas, data)
+			}
+		}
+	}
+
+	if data, ok := secret.Data[caFile]; ok {
+		cas = append(cas, data)
+	}
+
+	if data, ok := secret.Data[v1.TLSCertKey]; ok {
+		cert = data
+		key = secret.Data[v1.TLSPrivateKeyKey]
+	}
+
+	if len(cas) == 0 && cert == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cas) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		for _, ca := range cas {
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("invalid ca bundle of registry %s", registry)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cert != nil {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// remoteOptions appends the transport to the options of the remote calls.
+func (r *RevisionController) remoteOptions(opts ...remote.Option) []remote.Option {
+	if r.config.transport != nil {
+		opts = append(opts, remote.WithTransport(r.config.transport))
+	}
+
+	return opts
+}
+
+// mirrorReferences returns the references of the image on the mirrors of its registry,
+// followed by the reference itself.
+func (r *RevisionController) mirrorReferences(ref name.Reference) ([]name.Reference, error) {
+	var refs []name.Reference
+	for _, mirror := range r.config.mirrors[ref.Context().RegistryStr()] {
+		image := mirror + "/" + ref.Context().RepositoryStr() + strings.TrimPrefix(ref.Name(), ref.Context().Name())
+		mirrorRef, err := r.parseReference(image)
+		if err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, mirrorRef)
+	}
+
+	return append(refs, ref), nil
+}
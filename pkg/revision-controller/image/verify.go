@@ -0,0 +1,277 @@
+package image
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultCosignKey            = "cosign.pub"
+	defaultAttestationPredicate = "https://slsa.dev/provenance/v0.2"
+
+	signatureAnnotation  = "dev.cosignproject.cosign/signature"
+	signatureTagSuffix   = ".sig"
+	attestationTagSuffix = ".att"
+	dssePayloadType      = "application/vnd.in-toto+json"
+)
+
+// verifier verifies the cosign signature and optionally the attestation of an image digest.
+type verifier struct {
+	publicKey crypto.PublicKey
+	// predicateType is the predicate type of the attestation required, empty means no attestation is required.
+	predicateType string
+}
+
+// simpleSigning is the payload signed by cosign.
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// getVerifier returns the verifier if `cosign-key-secret` is specified, the public key is read from the secret.
+func (r *RevisionController) getVerifier(config map[string]string) (*verifier, error) {
+	secretName := config[constants.CosignKeySecret]
+	if secretName == "" {
+		return nil, nil
+	}
+
+	secret := &v1.Secret{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: r.fn.Namespace, Name: secretName}, secret); err != nil {
+		return nil, err
+	}
+
+	key := config[constants.CosignKey]
+	if key == "" {
+		key = defaultCosignKey
+	}
+
+	block, _ := pem.Decode(secret.Data[key])
+	if block == nil {
+		return nil, fmt.Errorf("no pem encoded public key found in secret %s", secretName)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &verifier{publicKey: publicKey}
+	if config[constants.VerifyAttestation] == "true" {
+		v.predicateType = config[constants.AttestationPredicate]
+		if v.predicateType == "" {
+			v.predicateType = defaultAttestationPredicate
+		}
+	}
+
+	return v, nil
+}
+
+// verifyDigests verifies the signature and the attestation of the image, the image is accepted if
+// any of its digests, the index digest or the manifest digest, is signed.
+func (r *RevisionController) verifyDigests(image string, digests *imageDigests) error {
+	ref, err := r.parseReference(image)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, digest := range []string{digests.Index, digests.Manifest} {
+		if digest == "" {
+			continue
+		}
+
+		err := r.verifySignature(ref.Context(), digest)
+		if err == nil && r.verifier.predicateType != "" {
+			err = r.verifyAttestation(ref.Context(), digest)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %v", digest, err))
+	}
+
+	return fmt.Errorf("verify image %s error, %s", image, strings.Join(errs, "; "))
+}
+
+func (r *RevisionController) verifySignature(repo name.Repository, digest string) error {
+	layers, err := r.fetchLayers(repo, digest, signatureTagSuffix)
+	if err != nil {
+		return err
+	}
+
+	for _, layer := range layers {
+		sig, err := base64.StdEncoding.DecodeString(layer.annotations[signatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+
+		if err := r.verifier.verify(layer.data, sig); err != nil {
+			continue
+		}
+
+		payload := &simpleSigning{}
+		if err := json.Unmarshal(layer.data, payload); err != nil {
+			continue
+		}
+
+		if payload.Critical.Image.DockerManifestDigest == digest {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s", "no valid signature found")
+}
+
+func (r *RevisionController) verifyAttestation(repo name.Repository, digest string) error {
+	layers, err := r.fetchLayers(repo, digest, attestationTagSuffix)
+	if err != nil {
+		return err
+	}
+
+	hex := strings.TrimPrefix(digest, "sha256:")
+	for _, layer := range layers {
+		envelope := &dsseEnvelope{}
+		if err := json.Unmarshal(layer.data, envelope); err != nil || envelope.PayloadType != dssePayloadType {
+			continue
+		}
+
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			continue
+		}
+
+		verified := false
+		pae := dssePAE(envelope.PayloadType, payload)
+		for _, signature := range envelope.Signatures {
+			sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+			if err == nil && r.verifier.verify(pae, sig) == nil {
+				verified = true
+				break
+			}
+		}
+
+		if !verified {
+			continue
+		}
+
+		statement := &inTotoStatement{}
+		if err := json.Unmarshal(payload, statement); err != nil || statement.PredicateType != r.verifier.predicateType {
+			continue
+		}
+
+		for _, subject := range statement.Subject {
+			if subject.Digest["sha256"] == hex {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("no valid attestation of predicate type %s found", r.verifier.predicateType)
+}
+
+type signedLayer struct {
+	annotations map[string]string
+	data        []byte
+}
+
+// fetchLayers fetches the layers of the cosign artifact `sha256-<hex><suffix>` of the digest.
+func (r *RevisionController) fetchLayers(repo name.Repository, digest string, suffix string) ([]signedLayer, error) {
+	tag := repo.Tag(strings.Replace(digest, ":", "-", 1) + suffix)
+	img, err := remote.Image(tag, r.remoteOptions(remote.WithAuthFromKeychain(r.keychain))...)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []signedLayer
+	for _, descriptor := range manifest.Layers {
+		layer, err := img.LayerByDigest(descriptor.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, signedLayer{
+			annotations: descriptor.Annotations,
+			data:        data,
+		})
+	}
+
+	return layers, nil
+}
+
+func (v *verifier) verify(data []byte, sig []byte) error {
+	hash := sha256.Sum256(data)
+	switch key := v.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hash[:], sig) {
+			return fmt.Errorf("%s", "invalid ecdsa signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return fmt.Errorf("%s", "invalid ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unspport public key type, %T", v.publicKey)
+	}
+}
+
+// dssePAE returns the pre-authentication encoding of the DSSE envelope.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
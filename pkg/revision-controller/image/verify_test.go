@@ -0,0 +1,250 @@
+package image
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestVerifyDigests(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/test/function"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedAttested := pushRandomImage(t, repo+":signed-attested")
+	pushSignature(t, repo, signedAttested, key)
+	pushAttestation(t, repo, signedAttested, key, defaultAttestationPredicate)
+
+	signed := pushRandomImage(t, repo+":signed")
+	pushSignature(t, repo, signed, key)
+
+	unsigned := pushRandomImage(t, repo+":unsigned")
+
+	signedByOther := pushRandomImage(t, repo+":signed-by-other")
+	pushSignature(t, repo, signedByOther, otherKey)
+
+	// The signature of another digest is copied to the digest.
+	copied := pushRandomImage(t, repo+":copied")
+	pushImage(t, signatureTag(repo, copied), signatureImage(t, signed, key))
+
+	tests := []struct {
+		name          string
+		tag           string
+		digest        string
+		predicateType string
+		accepted      bool
+	}{
+		{name: "signed", tag: "signed", digest: signed, accepted: true},
+		{name: "unsigned", tag: "unsigned", digest: unsigned},
+		{name: "signed by other key", tag: "signed-by-other", digest: signedByOther},
+		{name: "signature of other digest", tag: "copied", digest: copied},
+		{name: "signed and attested", tag: "signed-attested", digest: signedAttested, predicateType: defaultAttestationPredicate, accepted: true},
+		{name: "attestation missing", tag: "signed", digest: signed, predicateType: defaultAttestationPredicate},
+		{name: "attestation of other predicate", tag: "signed-attested", digest: signedAttested, predicateType: "https://example.com/other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RevisionController{
+				config:   &Config{imageConfig: imageConfig{insecure: true}},
+				keychain: &staticKeychain{auth: authn.Anonymous},
+				verifier: &verifier{publicKey: &key.PublicKey, predicateType: tt.predicateType},
+			}
+
+			err := r.verifyDigests(repo+":"+tt.tag, &imageDigests{Manifest: tt.digest})
+			if tt.accepted && err != nil {
+				t.Fatalf("expected the digest to be accepted, got %v", err)
+			}
+
+			if !tt.accepted && err == nil {
+				t.Fatal("expected the digest to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifierKeyTypes(t *testing.T) {
+	data := []byte("payload")
+	hash := sha256.Sum256(data)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSig, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Public, ed25519Private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Sig := ed25519.Sign(ed25519Private, data)
+
+	tests := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		sig       []byte
+	}{
+		{name: "ecdsa", publicKey: &ecdsaKey.PublicKey, sig: ecdsaSig},
+		{name: "rsa", publicKey: &rsaKey.PublicKey, sig: rsaSig},
+		{name: "ed25519", publicKey: ed25519Public, sig: ed25519Sig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &verifier{publicKey: tt.publicKey}
+			if err := v.verify(data, tt.sig); err != nil {
+				t.Fatalf("expected the signature to be valid, got %v", err)
+			}
+
+			if err := v.verify([]byte("tampered"), tt.sig); err == nil {
+				t.Fatal("expected the signature of the tampered data to be invalid")
+			}
+		})
+	}
+
+	v := &verifier{publicKey: "unknown"}
+	if err := v.verify(data, ecdsaSig); err == nil {
+		t.Fatal("expected the unknown key type to be rejected")
+	}
+}
+
+func TestDssePAE(t *testing.T) {
+	got := string(dssePAE(dssePayloadType, []byte("{}")))
+	want := "DSSEv1 28 application/vnd.in-toto+json 2 {}"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func pushRandomImage(t *testing.T, image string) string {
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushImage(t, image, img)
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest.String()
+}
+
+func pushImage(t *testing.T, image string, img gcrv1.Image) {
+	ref, err := name.ParseReference(image, name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func signatureTag(repo string, digest string) string {
+	return repo + ":" + strings.Replace(digest, ":", "-", 1) + signatureTagSuffix
+}
+
+// pushSignature pushes the cosign signature of the digest signed by the key.
+func pushSignature(t *testing.T, repo string, digest string, key *ecdsa.PrivateKey) {
+	pushImage(t, signatureTag(repo, digest), signatureImage(t, digest, key))
+}
+
+func signatureImage(t *testing.T, digest string, key *ecdsa.PrivateKey) gcrv1.Image {
+	payload := &simpleSigning{}
+	payload.Critical.Image.DockerManifestDigest = digest
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newSignedImage(t, data, map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sign(t, key, data)),
+	})
+}
+
+// pushAttestation pushes the DSSE envelope of the in-toto statement of the digest signed by the key.
+func pushAttestation(t *testing.T, repo string, digest string, key *ecdsa.PrivateKey, predicateType string) {
+	statement := fmt.Sprintf(`{"predicateType":%q,"subject":[{"digest":{"sha256":%q}}]}`,
+		predicateType, strings.TrimPrefix(digest, "sha256:"))
+	envelope := map[string]interface{}{
+		"payloadType": dssePayloadType,
+		"payload":     base64.StdEncoding.EncodeToString([]byte(statement)),
+		"signatures": []map[string]string{
+			{"sig": base64.StdEncoding.EncodeToString(sign(t, key, dssePAE(dssePayloadType, []byte(statement))))},
+		},
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := repo + ":" + strings.Replace(digest, ":", "-", 1) + attestationTagSuffix
+	pushImage(t, tag, newSignedImage(t, data, nil))
+}
+
+func newSignedImage(t *testing.T, data []byte, annotations map[string]string) gcrv1.Image {
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(data, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+		Annotations: annotations,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	hash := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sig
+}
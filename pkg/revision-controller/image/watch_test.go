@@ -0,0 +1,74 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+func TestWatchesImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		image      string
+		config     map[string]string
+		repository string
+		tag        string
+		watched    bool
+	}{
+		{name: "official image", image: "nginx", repository: "index.docker.io/library/nginx", tag: "latest", watched: true},
+		{name: "official image with library", image: "library/nginx:1.25", repository: "index.docker.io/library/nginx", tag: "1.25", watched: true},
+		{name: "docker.io host", image: "docker.io/openfunction/function:v1", repository: "index.docker.io/openfunction/function", tag: "v1", watched: true},
+		{name: "index.docker.io host", image: "index.docker.io/openfunction/function:v1", repository: "index.docker.io/openfunction/function", tag: "v1", watched: true},
+		{name: "other tag", image: "openfunction/function:v1", repository: "index.docker.io/openfunction/function", tag: "v2"},
+		{name: "any tag", image: "openfunction/function:v1", repository: "index.docker.io/openfunction/function", watched: true},
+		{name: "unrelated repository", image: "openfunction/function:v1", repository: "index.docker.io/openfunction/other", tag: "v1"},
+		{name: "same repository of other registry", image: "openfunction/function:v1", repository: "quay.io/openfunction/function", tag: "v1"},
+		{name: "registry with port", image: "registry.example.com:5000/openfunction/function:v2", repository: "registry.example.com:5000/openfunction/function", tag: "v2", watched: true},
+		{
+			name:       "mirror",
+			image:      "openfunction/function:v1",
+			config:     map[string]string{constants.RegistryMirrors: "docker.io=mirror.example.com"},
+			repository: "mirror.example.com/openfunction/function",
+			tag:        "v1",
+			watched:    true,
+		},
+		{
+			name:       "tag policy",
+			image:      "openfunction/function:v1.0.0",
+			config:     map[string]string{constants.SemverRange: ">=1.0.0"},
+			repository: "index.docker.io/openfunction/function",
+			tag:        "v1.2.0",
+			watched:    true,
+		},
+		{
+			name:       "tag out of policy",
+			image:      "openfunction/function:v1.0.0",
+			config:     map[string]string{constants.SemverRange: ">=1.0.0"},
+			repository: "index.docker.io/openfunction/function",
+			tag:        "v0.9.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newTagPolicy(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mirrors, err := getMirrors(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &RevisionController{
+				config: &Config{imageConfig: imageConfig{image: tt.image, mirrors: mirrors, tagPolicy: policy}},
+			}
+			r.setWatched()
+
+			if watched := r.WatchesImage(tt.repository, tt.tag); watched != tt.watched {
+				t.Fatalf("expected %v, got %v", tt.watched, watched)
+			}
+		})
+	}
+}
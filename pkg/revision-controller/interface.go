@@ -4,4 +4,13 @@ type RevisionController interface {
 	Start()
 	Update(config map[string]string) error
 	Stop()
+	// Trigger runs the check immediately instead of waiting for the next polling.
+	Trigger()
+}
+
+// ImageWatcher is implemented by the revision controllers which watch an image.
+type ImageWatcher interface {
+	// WatchesImage returns true if the image of the repository and tag is watched, the repository is
+	// the full name with the registry, such as `index.docker.io/library/nginx`, the tag can be empty.
+	WatchesImage(repository string, tag string) bool
 }
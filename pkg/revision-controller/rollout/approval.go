@@ -0,0 +1,104 @@
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsManualApproval returns true if the new revisions must be approved before they are applied.
+func IsManualApproval(config map[string]string) bool {
+	return config[constants.Approval] == constants.ApprovalManual
+}
+
+// GetRevisions returns the revisions recorded in the pending or the approved revision annotation,
+// which is a yaml map keyed by the revision controller types, such as `source`, `source/<name>` and `image`.
+func GetRevisions(function *openfunction.Function, annotation string) (map[string]string, error) {
+	revisions := make(map[string]string)
+	data := function.Annotations[annotation]
+	if data == "" {
+		return revisions, nil
+	}
+
+	if err := utils.YamlUnmarshal([]byte(data), revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// IsApproved returns true if the pending revision of the revision controller type is approved.
+func IsApproved(function *openfunction.Function, revisionControllerType string) bool {
+	pending, err := GetRevisions(function, constants.PendingRevisionAnnotation)
+	if err != nil || pending[revisionControllerType] == "" {
+		return false
+	}
+
+	approved, err := GetRevisions(function, constants.ApprovedRevisionAnnotation)
+	if err != nil {
+		return false
+	}
+
+	return approved[revisionControllerType] == pending[revisionControllerType]
+}
+
+// Approved returns true if the revision is approved by setting the revision controller type to it in the
+// approved revision annotation, otherwise the revision is recorded as the pending revision of the type and
+// an event is emitted, so that it can be found by `kubectl describe`.
+func Approved(c client.Client, recorder record.EventRecorder, function *openfunction.Function, revisionControllerType string, revision string) (bool, error) {
+	approved, err := GetRevisions(function, constants.ApprovedRevisionAnnotation)
+	if err != nil {
+		return false, err
+	}
+
+	if approved[revisionControllerType] == revision {
+		return true, nil
+	}
+
+	// The approvals of the other types are kept in the command.
+	approved[revisionControllerType] = revision
+	data, err := json.Marshal(approved)
+	if err != nil {
+		return false, err
+	}
+
+	return false, SetPending(c, recorder, function, revisionControllerType, revision,
+		fmt.Sprintf("%s revision %s is pending approval, approve it with `kubectl annotate --overwrite function %s %s='%s'`",
+			revisionControllerType, revision, function.Name, constants.ApprovedRevisionAnnotation, data))
+}
+
+// SetPending records the revision as the pending revision of the revision controller type, the message is
+// emitted as an event when the pending revision changes.
+func SetPending(c client.Client, recorder record.EventRecorder, function *openfunction.Function, revisionControllerType string, revision string, message string) error {
+	pending, err := GetRevisions(function, constants.PendingRevisionAnnotation)
+	if err != nil {
+		return err
+	}
+
+	if pending[revisionControllerType] == revision {
+		return nil
+	}
+
+	if err := PatchTypedAnnotation(c, function, constants.PendingRevisionAnnotation, revisionControllerType, revision); err != nil {
+		return err
+	}
+
+	recorder.Event(function, v1.EventTypeNormal, "RevisionPending", message)
+	return nil
+}
+
+// Complete clears the pending and the approved revision of the revision controller type after the revision
+// is applied, the revisions of the other types are kept.
+func Complete(c client.Client, function *openfunction.Function, revisionControllerType string) error {
+	if err := PatchTypedAnnotation(c, function, constants.PendingRevisionAnnotation, revisionControllerType, nil); err != nil {
+		return err
+	}
+
+	return PatchTypedAnnotation(c, function, constants.ApprovedRevisionAnnotation, revisionControllerType, nil)
+}
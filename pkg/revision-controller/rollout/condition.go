@@ -0,0 +1,27 @@
+package rollout
+
+import (
+	"time"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition is the abnormal condition of a revision controller, such as the watched branch is deleted.
+type Condition struct {
+	Reason  string `yaml:"reason"`
+	Message string `yaml:"message"`
+	Time    string `yaml:"time"`
+}
+
+// SetCondition records the condition of the revision controller type in the annotation of the function,
+// nil clears it.
+func SetCondition(c client.Client, fn *openfunction.Function, revisionControllerType string, condition *Condition) error {
+	if condition == nil {
+		return PatchTypedAnnotation(c, fn, constants.ConditionsAnnotation, revisionControllerType, nil)
+	}
+
+	condition.Time = time.Now().UTC().Format(time.RFC3339)
+	return PatchTypedAnnotation(c, fn, constants.ConditionsAnnotation, revisionControllerType, condition)
+}
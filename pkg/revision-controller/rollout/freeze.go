@@ -0,0 +1,43 @@
+package rollout
+
+import (
+	"fmt"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+// Freeze keeps the function at its current revision while the revision controller keeps polling.
+// When `paused` is true, no revision is applied. When `pin-revision` is set, only the pinned commit sha
+// or image digest is applied, the other revisions are reported as available updates.
+type Freeze struct {
+	Paused      bool
+	PinRevision string
+}
+
+// NewFreeze returns the freeze of the config, it's nil if the function is neither paused nor pinned.
+func NewFreeze(config map[string]string) *Freeze {
+	f := &Freeze{
+		Paused:      config[constants.Paused] == "true",
+		PinRevision: config[constants.PinRevision],
+	}
+
+	if !f.Paused && f.PinRevision == "" {
+		return nil
+	}
+
+	return f
+}
+
+// Blocks returns the reason why the revision is not applied, or empty if it can be applied.
+func (f *Freeze) Blocks(revision string) string {
+	switch {
+	case f == nil:
+		return ""
+	case f.Paused:
+		return "the revision controller is paused"
+	case f.PinRevision != revision:
+		return fmt.Sprintf("the function is pinned to revision %s", f.PinRevision)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,200 @@
+package rollout
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultHistoryLimit = 10
+
+	ReasonBaseline = "baseline"
+	ReasonInitial  = "initial"
+	ReasonChanged  = "changed"
+	ReasonNewTag   = "new-tag"
+	ReasonApproved = "approved"
+	ReasonRollback = "rollback"
+	ReasonRebuild  = "rebuild"
+)
+
+// Revision is a revision applied to the function by the revision controller.
+type Revision struct {
+	// Type is the type of the revision controller.
+	Type string `yaml:"type"`
+	// Revision is the commit sha or the image digest.
+	Revision string `yaml:"revision"`
+	// Image is the image of the digest, it's empty for the source revisions.
+	Image string `yaml:"image,omitempty"`
+	Time  string `yaml:"time"`
+	// Reason is why the revision is applied.
+	Reason string `yaml:"reason"`
+	// Outcome is the final state of the build or the serving of the revision, it's empty until it's known.
+	Outcome string `yaml:"outcome,omitempty"`
+}
+
+// GetHistoryLimit returns the number of revisions kept in the history, 0 means the history is disabled.
+func GetHistoryLimit(config map[string]string) (int, error) {
+	str := config[constants.HistoryLimit]
+	if str == "" {
+		return defaultHistoryLimit, nil
+	}
+
+	return strconv.Atoi(str)
+}
+
+// GetHistory returns the revision history recorded in the annotation of the function, the oldest first.
+func GetHistory(function *openfunction.Function) ([]Revision, error) {
+	var history []Revision
+	data := function.Annotations[constants.RevisionHistoryAnnotation]
+	if data == "" {
+		return history, nil
+	}
+
+	if err := utils.YamlUnmarshal([]byte(data), &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RecordRevision appends the revision to the history of the function, only the last `limit` revisions are kept.
+func RecordRevision(c client.Client, fn *openfunction.Function, revision Revision, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	revision.Time = time.Now().UTC().Format(time.RFC3339)
+	return patchHistory(c, fn, func(history []Revision) []Revision {
+		history = append(history, revision)
+		if len(history) > limit {
+			history = history[len(history)-limit:]
+		}
+
+		return history
+	})
+}
+
+// RecordOutcome fills the outcome of the last revision of the type if it's unknown yet, and returns the revision
+// with the outcome filled, or nil if no outcome is filled. The outcome is returned by the outcome function,
+// empty means it's still unknown.
+func RecordOutcome(c client.Client, fn *openfunction.Function, revisionControllerType string, outcome func(function *openfunction.Function) string) (*Revision, error) {
+	function := &openfunction.Function{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(fn), function); err != nil {
+		return nil, err
+	}
+
+	history, err := GetHistory(function)
+	if err != nil {
+		return nil, err
+	}
+
+	index := lastRevision(history, revisionControllerType)
+	if index < 0 || history[index].Outcome != "" {
+		return nil, nil
+	}
+
+	result := outcome(function)
+	if result == "" {
+		return nil, nil
+	}
+
+	var revision *Revision
+	err = patchHistory(c, fn, func(history []Revision) []Revision {
+		if index := lastRevision(history, revisionControllerType); index >= 0 && history[index].Outcome == "" {
+			history[index].Outcome = result
+			item := history[index]
+			revision = &item
+		}
+
+		return history
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// BuildOutcome returns the final state of the build, or empty if the build is not finished.
+func BuildOutcome(function *openfunction.Function) string {
+	if function.Status.Build == nil {
+		return ""
+	}
+
+	switch function.Status.Build.State {
+	case openfunction.Succeeded, openfunction.Failed, openfunction.Timeout, openfunction.Canceled, openfunction.Skipped:
+		return function.Status.Build.State
+	default:
+		return ""
+	}
+}
+
+// BuildInProgress returns true if the build of the function is not finished.
+func BuildInProgress(function *openfunction.Function) bool {
+	if function.Status.Build == nil {
+		return false
+	}
+
+	switch function.Status.Build.State {
+	case "", openfunction.Created, openfunction.Building:
+		return true
+	default:
+		return false
+	}
+}
+
+// ServingOutcome returns the final state of the serving, or empty if the serving is not started yet.
+func ServingOutcome(function *openfunction.Function) string {
+	if function.Status.Serving == nil {
+		return ""
+	}
+
+	switch function.Status.Serving.State {
+	case openfunction.Running, openfunction.Failed:
+		return function.Status.Serving.State
+	default:
+		return ""
+	}
+}
+
+func lastRevision(history []Revision, revisionControllerType string) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Type == revisionControllerType {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// patchHistory patches the history annotation of the latest function with the optimistic lock,
+// the patch is retried on conflict since the history may be written by multiple revision controllers.
+func patchHistory(c client.Client, fn *openfunction.Function, mutate func(history []Revision) []Revision) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		function := &openfunction.Function{}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(fn), function); err != nil {
+			return err
+		}
+
+		// The malformed history is dropped.
+		history, _ := GetHistory(function)
+		data, err := utils.YamlMarshal(mutate(history))
+		if err != nil {
+			return err
+		}
+
+		patch := client.MergeFromWithOptions(function.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if function.Annotations == nil {
+			function.Annotations = make(map[string]string)
+		}
+		function.Annotations[constants.RevisionHistoryAnnotation] = string(data)
+		return c.Patch(context.Background(), function, patch)
+	})
+}
@@ -0,0 +1,57 @@
+package rollout
+
+import (
+	"testing"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+func TestPatchHistory(t *testing.T) {
+	c, fn := newConflictingClient(t, func(function *openfunction.Function) {
+		function.Status.Build = &openfunction.Condition{State: openfunction.Succeeded}
+		function.Annotations = map[string]string{
+			constants.RevisionHistoryAnnotation: "- type: image\n  revision: sha256:a\n  time: \"\"\n  reason: changed\n",
+		}
+	})
+
+	if err := RecordRevision(c, fn, Revision{Type: "source", Revision: "b", Reason: ReasonChanged}, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.gets != 2 {
+		t.Fatalf("expected the patch to be retried once, got %d gets", c.gets)
+	}
+
+	function := getFunction(t, c.Client, fn)
+	if function.Status.Build == nil || function.Status.Build.State != openfunction.Succeeded {
+		t.Fatalf("expected the build written by the other client to be kept, got %v", function.Status.Build)
+	}
+
+	history, err := GetHistory(function)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 2 || history[0].Revision != "sha256:a" || history[1].Revision != "b" {
+		t.Fatalf("expected the revisions recorded by both clients, got %v", history)
+	}
+}
+
+func TestRecordRevisionLimit(t *testing.T) {
+	c, fn := newConflictingClient(t, nil)
+	for _, revision := range []string{"a", "b", "c"} {
+		if err := RecordRevision(c, fn, Revision{Type: "source", Revision: revision}, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := GetHistory(getFunction(t, c.Client, fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 2 || history[0].Revision != "b" || history[1].Revision != "c" {
+		t.Fatalf("expected the last 2 revisions, got %v", history)
+	}
+}
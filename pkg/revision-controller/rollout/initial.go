@@ -0,0 +1,27 @@
+package rollout
+
+import (
+	"fmt"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+// GetInitialRevisionPolicy returns how the revision detected when the function has no revision yet is handled:
+// `baseline` records it as the current revision without rebuilding or redeploying, `wait` waits for the first
+// build or deployment to record it, and `trigger` applies it as a new revision. The default is `baseline` for
+// the source revision controller, and `trigger` for the image and source-image revision controllers, which
+// redeploy or rebuild the function when no digest is recorded as they did before the policy is configurable.
+func GetInitialRevisionPolicy(config map[string]string) (string, error) {
+	policy := config[constants.InitialRevision]
+	switch policy {
+	case "":
+		if config[constants.RevisionControllerType] == constants.RevisionControllerTypeSource {
+			return constants.InitialRevisionBaseline, nil
+		}
+		return constants.InitialRevisionTrigger, nil
+	case constants.InitialRevisionBaseline, constants.InitialRevisionWait, constants.InitialRevisionTrigger:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unspport initial revision policy, %s", policy)
+	}
+}
@@ -0,0 +1,179 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/openfunction/revision-controller/pkg/utils"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultRollbackCooldown = 30 * time.Minute
+)
+
+// RollbackPolicy rolls the function back to the last known-good revision when the build or the serving of
+// a new revision fails, the new revisions are not applied until the cooldown ends.
+type RollbackPolicy struct {
+	Cooldown time.Duration
+}
+
+// RollbackState is the rollback in progress of a revision controller.
+type RollbackState struct {
+	// Revision is the known-good revision rolled back to.
+	Revision string `yaml:"revision"`
+	// Failed is the revision failed.
+	Failed string `yaml:"failed"`
+	// Original is the original source revision or image of the function replaced by the pinned commit or digest,
+	// it's nil if the default branch is used.
+	Original *string `yaml:"original,omitempty"`
+	// Until is the end of the cooldown.
+	Until string `yaml:"until"`
+}
+
+// NewRollbackPolicy returns the rollback policy, it's nil if the automatic rollback is not enabled.
+func NewRollbackPolicy(config map[string]string) (*RollbackPolicy, error) {
+	switch config[constants.Rollback] {
+	case "":
+		return nil, nil
+	case constants.RollbackAuto:
+	default:
+		return nil, fmt.Errorf("unspport rollback policy, %s", config[constants.Rollback])
+	}
+
+	p := &RollbackPolicy{Cooldown: defaultRollbackCooldown}
+	if str := config[constants.RollbackCooldown]; str != "" {
+		var err error
+		p.Cooldown, err = time.ParseDuration(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// ShouldRollback returns true if the revision failed and it's not a rollback itself,
+// so that the rollbacks are not chained.
+func (p *RollbackPolicy) ShouldRollback(revision *Revision) bool {
+	if p == nil || revision == nil || revision.Reason == ReasonRollback {
+		return false
+	}
+
+	switch revision.Outcome {
+	case openfunction.Failed, openfunction.Timeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewState returns the state of the rollback from the failed revision to the good revision.
+func (p *RollbackPolicy) NewState(good *Revision, failed *Revision) *RollbackState {
+	return &RollbackState{
+		Revision: good.Revision,
+		Failed:   failed.Revision,
+		Until:    time.Now().Add(p.Cooldown).UTC().Format(time.RFC3339),
+	}
+}
+
+// InCooldown returns true if the cooldown of the rollback is not over.
+func (s *RollbackState) InCooldown() bool {
+	until, err := time.Parse(time.RFC3339, s.Until)
+	return err == nil && time.Now().Before(until)
+}
+
+// LastGood returns the last revision of the type which is built or served successfully,
+// and is not the failed revision.
+func LastGood(function *openfunction.Function, revisionControllerType string, failed string) (*Revision, error) {
+	history, err := GetHistory(function)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		item := history[i]
+		if item.Type != revisionControllerType || item.Revision == failed {
+			continue
+		}
+
+		if item.Outcome == openfunction.Succeeded || item.Outcome == openfunction.Running {
+			return &item, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetRollback returns the rollback in progress of the revision controller type, or nil if there is none.
+func GetRollback(function *openfunction.Function, revisionControllerType string) (*RollbackState, error) {
+	data := function.Annotations[constants.RollbackAnnotation]
+	if data == "" {
+		return nil, nil
+	}
+
+	states := make(map[string]*RollbackState)
+	if err := utils.YamlUnmarshal([]byte(data), states); err != nil {
+		return nil, err
+	}
+
+	return states[revisionControllerType], nil
+}
+
+// SetRollback records the rollback in progress of the revision controller type, nil clears it.
+func SetRollback(c client.Client, fn *openfunction.Function, revisionControllerType string, state *RollbackState) error {
+	if state == nil {
+		return PatchTypedAnnotation(c, fn, constants.RollbackAnnotation, revisionControllerType, nil)
+	}
+
+	return PatchTypedAnnotation(c, fn, constants.RollbackAnnotation, revisionControllerType, state)
+}
+
+// PatchTypedAnnotation sets the value of the revision controller type in the annotation, which is a yaml map
+// keyed by the revision controller types, nil removes the value. The annotation of the latest function is patched
+// with the optimistic lock, and the patch is retried on conflict.
+func PatchTypedAnnotation(c client.Client, fn *openfunction.Function, annotation string, revisionControllerType string, value interface{}) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		function := &openfunction.Function{}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(fn), function); err != nil {
+			return err
+		}
+
+		items := make(map[string]interface{})
+		if data := function.Annotations[annotation]; data != "" {
+			if err := utils.YamlUnmarshal([]byte(data), items); err != nil {
+				return err
+			}
+		}
+
+		if value == nil {
+			if _, ok := items[revisionControllerType]; !ok {
+				return nil
+			}
+			delete(items, revisionControllerType)
+		} else {
+			items[revisionControllerType] = value
+		}
+
+		patch := client.MergeFromWithOptions(function.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if len(items) == 0 {
+			delete(function.Annotations, annotation)
+		} else {
+			data, err := utils.YamlMarshal(items)
+			if err != nil {
+				return err
+			}
+
+			if function.Annotations == nil {
+				function.Annotations = make(map[string]string)
+			}
+			function.Annotations[annotation] = string(data)
+		}
+
+		return c.Patch(context.Background(), function, patch)
+	})
+}
@@ -0,0 +1,59 @@
+package rollout
+
+import (
+	"testing"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+func TestPatchTypedAnnotation(t *testing.T) {
+	c, fn := newConflictingClient(t, func(function *openfunction.Function) {
+		function.Status.Sources = []openfunction.SourceResult{{Name: "default"}}
+		function.Annotations = map[string]string{
+			constants.RollbackAnnotation: "image:\n  revision: sha256:a\n  failed: sha256:b\n  until: \"\"\n",
+		}
+	})
+
+	state := &RollbackState{Revision: "a", Failed: "b"}
+	if err := SetRollback(c, fn, constants.RevisionControllerTypeSource, state); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.gets != 2 {
+		t.Fatalf("expected the patch to be retried once, got %d gets", c.gets)
+	}
+
+	function := getFunction(t, c.Client, fn)
+	if len(function.Status.Sources) != 1 {
+		t.Fatalf("expected the sources written by the other client to be kept, got %v", function.Status.Sources)
+	}
+
+	image, err := GetRollback(function, constants.RevisionControllerTypeImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image == nil || image.Revision != "sha256:a" {
+		t.Fatalf("expected the image rollback written by the other client to be kept, got %v", image)
+	}
+
+	source, err := GetRollback(function, constants.RevisionControllerTypeSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source == nil || source.Revision != "a" || source.Failed != "b" {
+		t.Fatalf("expected the source rollback, got %v", source)
+	}
+
+	if err := SetRollback(c, fn, constants.RevisionControllerTypeImage, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRollback(c, fn, constants.RevisionControllerTypeSource, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	function = getFunction(t, c.Client, fn)
+	if _, ok := function.Annotations[constants.RollbackAnnotation]; ok {
+		t.Fatal("expected the annotation to be removed with the last rollback")
+	}
+}
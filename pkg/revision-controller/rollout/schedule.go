@@ -0,0 +1,136 @@
+package rollout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultScheduleWindow = time.Hour
+
+	// scheduleSeparator separates the cron expressions, the comma is used by the cron expressions.
+	scheduleSeparator = ";"
+	// blackoutSeparator separates the cron expression and the duration of a blackout window.
+	blackoutSeparator = "|"
+)
+
+// Schedule decides when the new revisions can be applied. The revisions are applied inside the windows
+// started by the `schedule` cron expressions and lasting `schedule-window`, and never inside the
+// `blackout-windows`, each of which is a cron expression and a duration, such as `0 9 * * 1-5|8h`.
+// The cron expressions are evaluated in the `timezone`, UTC by default.
+type Schedule struct {
+	location  *time.Location
+	windows   []cron.Schedule
+	window    time.Duration
+	blackouts []blackoutWindow
+}
+
+type blackoutWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// NewSchedule returns the schedule of the config, it's nil if neither schedule nor blackout window is specified.
+func NewSchedule(config map[string]string) (*Schedule, error) {
+	windows := splitSchedule(config[constants.Schedule])
+	blackouts := splitSchedule(config[constants.BlackoutWindows])
+	if len(windows) == 0 && len(blackouts) == 0 {
+		return nil, nil
+	}
+
+	s := &Schedule{
+		location: time.UTC,
+		window:   defaultScheduleWindow,
+	}
+
+	if str := config[constants.Timezone]; str != "" {
+		var err error
+		s.location, err = time.LoadLocation(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if str := config[constants.ScheduleWindow]; str != "" {
+		var err error
+		s.window, err = time.ParseDuration(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range windows {
+		schedule, err := cron.ParseStandard(item)
+		if err != nil {
+			return nil, err
+		}
+		s.windows = append(s.windows, schedule)
+	}
+
+	for _, item := range blackouts {
+		index := strings.LastIndex(item, blackoutSeparator)
+		if index < 0 {
+			return nil, fmt.Errorf("invalid blackout window, %s", item)
+		}
+
+		schedule, err := cron.ParseStandard(strings.TrimSpace(item[:index]))
+		if err != nil {
+			return nil, err
+		}
+
+		duration, err := time.ParseDuration(strings.TrimSpace(item[index+1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		s.blackouts = append(s.blackouts, blackoutWindow{schedule: schedule, duration: duration})
+	}
+
+	return s, nil
+}
+
+// Allowed returns true if the new revisions can be applied at the time.
+func (s *Schedule) Allowed(now time.Time) bool {
+	if s == nil {
+		return true
+	}
+
+	now = now.In(s.location)
+	for _, blackout := range s.blackouts {
+		if inWindow(blackout.schedule, blackout.duration, now) {
+			return false
+		}
+	}
+
+	if len(s.windows) == 0 {
+		return true
+	}
+
+	for _, window := range s.windows {
+		if inWindow(window, s.window, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inWindow returns true if the schedule is activated in the duration before the time.
+func inWindow(schedule cron.Schedule, duration time.Duration, now time.Time) bool {
+	return !schedule.Next(now.Add(-duration)).After(now)
+}
+
+func splitSchedule(str string) []string {
+	var items []string
+	for _, item := range strings.Split(str, scheduleSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
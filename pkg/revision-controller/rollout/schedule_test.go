@@ -0,0 +1,201 @@
+package rollout
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/openfunction/revision-controller/pkg/constants"
+)
+
+func TestScheduleAllowed(t *testing.T) {
+	at := func(value string) time.Time {
+		now, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return now
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		now     string
+		allowed bool
+	}{
+		{
+			name:   "before the window",
+			config: map[string]string{constants.Schedule: "0 2 * * *"},
+			now:    "2023-04-03T01:59:59Z",
+		},
+		{
+			name:    "start of the window",
+			config:  map[string]string{constants.Schedule: "0 2 * * *"},
+			now:     "2023-04-03T02:00:00Z",
+			allowed: true,
+		},
+		{
+			name:    "end of the window",
+			config:  map[string]string{constants.Schedule: "0 2 * * *"},
+			now:     "2023-04-03T02:59:59Z",
+			allowed: true,
+		},
+		{
+			name:   "after the window",
+			config: map[string]string{constants.Schedule: "0 2 * * *"},
+			now:    "2023-04-03T03:00:00Z",
+		},
+		{
+			name:    "custom window",
+			config:  map[string]string{constants.Schedule: "0 2 * * *", constants.ScheduleWindow: "30m"},
+			now:     "2023-04-03T02:29:59Z",
+			allowed: true,
+		},
+		{
+			name:   "after the custom window",
+			config: map[string]string{constants.Schedule: "0 2 * * *", constants.ScheduleWindow: "30m"},
+			now:    "2023-04-03T02:30:00Z",
+		},
+		{
+			name:    "second window",
+			config:  map[string]string{constants.Schedule: "0 2 * * *; 0 14 * * *"},
+			now:     "2023-04-03T14:10:00Z",
+			allowed: true,
+		},
+		{
+			name:    "window across midnight before midnight",
+			config:  map[string]string{constants.Schedule: "0 23 * * *", constants.ScheduleWindow: "2h"},
+			now:     "2023-04-03T23:30:00Z",
+			allowed: true,
+		},
+		{
+			name:    "window across midnight after midnight",
+			config:  map[string]string{constants.Schedule: "0 23 * * *", constants.ScheduleWindow: "2h"},
+			now:     "2023-04-04T00:59:59Z",
+			allowed: true,
+		},
+		{
+			name:   "after the window across midnight",
+			config: map[string]string{constants.Schedule: "0 23 * * *", constants.ScheduleWindow: "2h"},
+			now:    "2023-04-04T01:00:00Z",
+		},
+		{
+			name:    "window across midnight on the weekday only",
+			config:  map[string]string{constants.Schedule: "0 23 * * 5", constants.ScheduleWindow: "2h"},
+			now:     "2023-04-08T00:30:00Z",
+			allowed: true,
+		},
+		{
+			name:   "window across midnight on the other weekday",
+			config: map[string]string{constants.Schedule: "0 23 * * 5", constants.ScheduleWindow: "2h"},
+			now:    "2023-04-09T00:30:00Z",
+		},
+		{
+			name:    "before the blackout",
+			config:  map[string]string{constants.BlackoutWindows: "0 9 * * *|8h; 0 12 * * *|8h"},
+			now:     "2023-04-03T08:59:59Z",
+			allowed: true,
+		},
+		{
+			name:   "start of the blackout",
+			config: map[string]string{constants.BlackoutWindows: "0 9 * * *|8h; 0 12 * * *|8h"},
+			now:    "2023-04-03T09:00:00Z",
+		},
+		{
+			name:   "overlapping blackouts",
+			config: map[string]string{constants.BlackoutWindows: "0 9 * * *|8h; 0 12 * * *|8h"},
+			now:    "2023-04-03T17:00:00Z",
+		},
+		{
+			name:    "after the overlapping blackouts",
+			config:  map[string]string{constants.BlackoutWindows: "0 9 * * *|8h; 0 12 * * *|8h"},
+			now:     "2023-04-03T20:00:00Z",
+			allowed: true,
+		},
+		{
+			name:   "blackout inside the window",
+			config: map[string]string{constants.Schedule: "0 0 * * *", constants.ScheduleWindow: "24h", constants.BlackoutWindows: "0 12 * * *|1h"},
+			now:    "2023-04-03T12:30:00Z",
+		},
+		{
+			name:    "window after the blackout",
+			config:  map[string]string{constants.Schedule: "0 0 * * *", constants.ScheduleWindow: "24h", constants.BlackoutWindows: "0 12 * * *|1h"},
+			now:     "2023-04-03T13:00:00Z",
+			allowed: true,
+		},
+		{
+			name:    "window in the timezone",
+			config:  map[string]string{constants.Schedule: "0 2 * * *", constants.Timezone: "Asia/Shanghai"},
+			now:     "2023-04-02T18:30:00Z",
+			allowed: true,
+		},
+		{
+			name:   "window in utc but not in the timezone",
+			config: map[string]string{constants.Schedule: "0 2 * * *", constants.Timezone: "Asia/Shanghai"},
+			now:    "2023-04-03T02:30:00Z",
+		},
+		{
+			name:   "blackout in the timezone",
+			config: map[string]string{constants.BlackoutWindows: "0 9 * * 1-5|8h", constants.Timezone: "America/New_York"},
+			now:    "2023-04-03T13:30:00Z",
+		},
+		{
+			name:    "weekend in the timezone",
+			config:  map[string]string{constants.BlackoutWindows: "0 9 * * 1-5|8h", constants.Timezone: "America/New_York"},
+			now:     "2023-04-03T03:00:00Z",
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSchedule(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if allowed := s.Allowed(at(tt.now)); allowed != tt.allowed {
+				t.Fatalf("expected %v at %s, got %v", tt.allowed, tt.now, allowed)
+			}
+		})
+	}
+}
+
+func TestNewScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+	}{
+		{name: "invalid cron", config: map[string]string{constants.Schedule: "61 * * * *"}},
+		{name: "too few cron fields", config: map[string]string{constants.Schedule: "0 2 * *"}},
+		{name: "invalid window", config: map[string]string{constants.Schedule: "0 2 * * *", constants.ScheduleWindow: "1x"}},
+		{name: "invalid timezone", config: map[string]string{constants.Schedule: "0 2 * * *", constants.Timezone: "Mars/Olympus"}},
+		{name: "blackout without duration", config: map[string]string{constants.BlackoutWindows: "0 9 * * *"}},
+		{name: "blackout with invalid duration", config: map[string]string{constants.BlackoutWindows: "0 9 * * *|8x"}},
+		{name: "blackout with invalid cron", config: map[string]string{constants.BlackoutWindows: "0 25 * * *|8h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSchedule(tt.config); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewScheduleEmpty(t *testing.T) {
+	s, err := NewSchedule(map[string]string{constants.Schedule: " ; ", constants.Timezone: "Asia/Shanghai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != nil {
+		t.Fatal("expected no schedule")
+	}
+
+	if !s.Allowed(time.Now()) {
+		t.Fatal("expected the revisions to be allowed without schedule")
+	}
+}
@@ -0,0 +1,37 @@
+package rollout
+
+import (
+	"context"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchStatus patches the status fields of the function changed by the mutate function. The patch is based on
+// the latest function with the optimistic lock, and is retried on conflict, so that the fields written by the
+// OpenFunction controller concurrently are neither clobbered nor overwritten with the stale values.
+func PatchStatus(c client.Client, fn *openfunction.Function, mutate func(function *openfunction.Function)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		function := &openfunction.Function{}
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(fn), function); err != nil {
+			return err
+		}
+
+		patch := client.MergeFromWithOptions(function.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		mutate(function)
+		return c.Status().Patch(context.Background(), function, patch)
+	})
+}
+
+// SetSource sets the result of the source with the same name, the results of the other sources are kept.
+func SetSource(function *openfunction.Function, source openfunction.SourceResult) {
+	for i := range function.Status.Sources {
+		if function.Status.Sources[i].Name == source.Name {
+			function.Status.Sources[i] = source
+			return
+		}
+	}
+
+	function.Status.Sources = append(function.Status.Sources, source)
+}
@@ -0,0 +1,118 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+
+	openfunction "github.com/openfunction/apis/core/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// conflictingClient writes the function by another client after the first get, so that the resource version
+// is bumped between the get and the patch, and the patch based on the first get conflicts.
+type conflictingClient struct {
+	client.Client
+	gets  int
+	write func(function *openfunction.Function)
+}
+
+func (c *conflictingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if err := c.Client.Get(ctx, key, obj); err != nil {
+		return err
+	}
+
+	c.gets++
+	if c.gets > 1 || c.write == nil {
+		return nil
+	}
+
+	function := &openfunction.Function{}
+	if err := c.Client.Get(ctx, key, function); err != nil {
+		return err
+	}
+
+	c.write(function)
+	return c.Client.Update(ctx, function)
+}
+
+func newConflictingClient(t *testing.T, write func(function *openfunction.Function)) (*conflictingClient, *openfunction.Function) {
+	scheme := runtime.NewScheme()
+	if err := openfunction.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := &openfunction.Function{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "function",
+			Namespace: "default",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(fn.DeepCopy()).Build()
+	return &conflictingClient{Client: c, write: write}, fn
+}
+
+func getFunction(t *testing.T, c client.Client, fn *openfunction.Function) *openfunction.Function {
+	function := &openfunction.Function{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(fn), function); err != nil {
+		t.Fatal(err)
+	}
+
+	return function
+}
+
+func TestPatchStatus(t *testing.T) {
+	c, fn := newConflictingClient(t, func(function *openfunction.Function) {
+		function.Status.Build = &openfunction.Condition{State: openfunction.Building}
+		function.Status.Sources = []openfunction.SourceResult{{Name: "other"}}
+	})
+
+	err := PatchStatus(c, fn, func(function *openfunction.Function) {
+		SetSource(function, openfunction.SourceResult{Name: "default"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.gets != 2 {
+		t.Fatalf("expected the patch to be retried once, got %d gets", c.gets)
+	}
+
+	function := getFunction(t, c.Client, fn)
+	if function.Status.Build == nil || function.Status.Build.State != openfunction.Building {
+		t.Fatalf("expected the build written by the other client to be kept, got %v", function.Status.Build)
+	}
+
+	if len(function.Status.Sources) != 2 || function.Status.Sources[0].Name != "other" || function.Status.Sources[1].Name != "default" {
+		t.Fatalf("expected the sources written by both clients, got %v", function.Status.Sources)
+	}
+}
+
+func TestSetSource(t *testing.T) {
+	function := &openfunction.Function{}
+	SetSource(function, gitSourceResult("default", "a"))
+	SetSource(function, gitSourceResult("lib", "b"))
+	SetSource(function, gitSourceResult("default", "c"))
+
+	if len(function.Status.Sources) != 2 {
+		t.Fatalf("expected 2 sources, got %d", len(function.Status.Sources))
+	}
+
+	if sha := function.Status.Sources[0].Git.CommitSha; sha != "c" {
+		t.Fatalf("expected the default source to be replaced, got %s", sha)
+	}
+
+	if sha := function.Status.Sources[1].Git.CommitSha; sha != "b" {
+		t.Fatalf("expected the lib source to be kept, got %s", sha)
+	}
+}
+
+func gitSourceResult(name string, sha string) openfunction.SourceResult {
+	return openfunction.SourceResult{
+		Name: name,
+		Git:  &openfunction.GitSourceResult{CommitSha: sha},
+	}
+}
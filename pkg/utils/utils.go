@@ -1,6 +1,10 @@
 package utils
 
-import "gopkg.in/yaml.v3"
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
 
 func StringInList(s string, list []string) bool {
 	for _, v := range list {
@@ -12,6 +16,25 @@ func StringInList(s string, list []string) bool {
 	return false
 }
 
+// SplitList splits a comma separated list or a yaml list, such as the list params kept in yaml by
+// YamlUnmarshalParams, the empty items are dropped.
+func SplitList(s string) []string {
+	var items []string
+	if err := yaml.Unmarshal([]byte(s), &items); err != nil {
+		items = strings.Split(s, ",")
+	}
+
+	var list []string
+	for _, v := range items {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
 func YamlMarshal(v interface{}) ([]byte, error) {
 	return yaml.Marshal(v)
 }
@@ -19,3 +42,28 @@ func YamlMarshal(v interface{}) ([]byte, error) {
 func YamlUnmarshal(data []byte, v interface{}) error {
 	return yaml.Unmarshal(data, v)
 }
+
+// YamlUnmarshalParams unmarshals the params in yaml, the structured values, such as the lists and the maps,
+// are kept in yaml, so that they can be written without the block string.
+func YamlUnmarshalParams(data []byte) (map[string]string, error) {
+	nodes := make(map[string]yaml.Node)
+	if err := yaml.Unmarshal(data, nodes); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string)
+	for k, node := range nodes {
+		if node.Kind == yaml.ScalarNode {
+			params[k] = node.Value
+			continue
+		}
+
+		value, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = string(value)
+	}
+
+	return params, nil
+}
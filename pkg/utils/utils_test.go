@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		list  []string
+	}{
+		{value: ""},
+		{value: "source", list: []string{"source"}},
+		{value: "source, image,", list: []string{"source", "image"}},
+		{value: "- source\n- image\n", list: []string{"source", "image"}},
+		{value: "[source, image]", list: []string{"source", "image"}},
+	}
+
+	for _, tt := range tests {
+		if list := SplitList(tt.value); !reflect.DeepEqual(list, tt.list) {
+			t.Errorf("SplitList(%q) = %v, expected %v", tt.value, list, tt.list)
+		}
+	}
+}
+
+func TestYamlUnmarshalParams(t *testing.T) {
+	params, err := YamlUnmarshalParams([]byte("type:\n  - source\n  - image\npaused: true\nsources:\n  - name: lib\n    url: https://github.com/owner/lib.git\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := SplitList(params["type"]); !reflect.DeepEqual(list, []string{"source", "image"}) {
+		t.Fatalf("expected the type list, got %v", list)
+	}
+
+	if params["paused"] != "true" {
+		t.Fatalf("expected the scalar as it is, got %q", params["paused"])
+	}
+
+	if params["sources"] != "- name: lib\n  url: https://github.com/owner/lib.git\n" {
+		t.Fatalf("expected the sources in yaml, got %q", params["sources"])
+	}
+}
@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	revisioncontroller "github.com/openfunction/revision-controller/pkg/revision-controller"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	registryPath = "/registry"
+
+	// maxPayloadSize limits the size of the payloads of the registry events.
+	maxPayloadSize = 1 << 20
+
+	distributionPushAction = "push"
+	harborPushEvent        = "PUSH_ARTIFACT"
+)
+
+// RevisionControllerLister lists the running revision controllers.
+type RevisionControllerLister interface {
+	RevisionControllers() []revisioncontroller.RevisionController
+}
+
+// RegistryReceiver receives the push events of the image registries, and triggers the revision controllers
+// watching the pushed images to check the digest immediately instead of waiting for the next polling.
+// The events of Harbor, Docker Hub, Quay and the CNCF distribution registry are supported.
+type RegistryReceiver struct {
+	addr   string
+	token  string
+	lister RevisionControllerLister
+	log    logr.Logger
+}
+
+// registryEvent is a pushed image, the repository is the full name with the registry.
+type registryEvent struct {
+	repository string
+	tag        string
+}
+
+// harborPayload is the payload of the Harbor webhook.
+type harborPayload struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data"`
+}
+
+// dockerHubPayload is the payload of the Docker Hub webhook.
+type dockerHubPayload struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// quayPayload is the payload of the Quay repository push notification.
+type quayPayload struct {
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// distributionPayload is the notification envelope of the CNCF distribution registry.
+type distributionPayload struct {
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+		Request struct {
+			Host string `json:"host"`
+		} `json:"request"`
+	} `json:"events"`
+}
+
+func NewRegistryReceiver(addr string, token string, lister RevisionControllerLister) *RegistryReceiver {
+	return &RegistryReceiver{
+		addr:   addr,
+		token:  token,
+		lister: lister,
+		log:    ctrl.Log.WithName("webhook").WithName("Registry"),
+	}
+}
+
+// Start runs the webhook server until the context is done.
+func (r *RegistryReceiver) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(registryPath, r)
+	server := &http.Server{
+		Addr:              r.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	r.log.Info("registry webhook receiver started", "addr", r.addr, "path", registryPath)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RegistryReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.authorized(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxPayloadSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	events, err := parseRegistryEvents(data)
+	if err != nil {
+		r.log.V(1).Info("parse registry event error", "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range events {
+		triggered := 0
+		for _, rc := range r.lister.RevisionControllers() {
+			watcher, ok := rc.(revisioncontroller.ImageWatcher)
+			if !ok || !watcher.WatchesImage(event.repository, event.tag) {
+				continue
+			}
+
+			rc.Trigger()
+			triggered++
+		}
+
+		r.log.V(1).Info("image pushed", "repository", event.repository, "tag", event.tag, "triggered", triggered)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorized checks the token in the `Authorization` header or the `token` query param,
+// the latter is used by the registries which can not set headers, such as Docker Hub.
+// All the requests are refused if no token is set.
+func (r *RegistryReceiver) authorized(req *http.Request) bool {
+	if r.token == "" {
+		return false
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = req.URL.Query().Get("token")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) == 1
+}
+
+// parseRegistryEvents parses the pushed images from the payload, the format is detected by the fields present.
+func parseRegistryEvents(data []byte) ([]registryEvent, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var events []registryEvent
+	switch {
+	case raw["event_data"] != nil:
+		payload := &harborPayload{}
+		if err := json.Unmarshal(data, payload); err != nil {
+			return nil, err
+		}
+
+		if payload.Type != harborPushEvent {
+			return nil, nil
+		}
+
+		for _, resource := range payload.EventData.Resources {
+			ref, err := name.ParseReference(resource.ResourceURL, name.WeakValidation)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, registryEvent{repository: ref.Context().Name(), tag: resource.Tag})
+		}
+	case raw["push_data"] != nil:
+		payload := &dockerHubPayload{}
+		if err := json.Unmarshal(data, payload); err != nil {
+			return nil, err
+		}
+
+		event, err := newRegistryEvent(payload.Repository.RepoName, payload.PushData.Tag)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	case raw["docker_url"] != nil:
+		payload := &quayPayload{}
+		if err := json.Unmarshal(data, payload); err != nil {
+			return nil, err
+		}
+
+		for _, tag := range payload.UpdatedTags {
+			event, err := newRegistryEvent(payload.DockerURL, tag)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+	case raw["events"] != nil:
+		payload := &distributionPayload{}
+		if err := json.Unmarshal(data, payload); err != nil {
+			return nil, err
+		}
+
+		for _, item := range payload.Events {
+			if item.Action != distributionPushAction || item.Request.Host == "" {
+				continue
+			}
+
+			event, err := newRegistryEvent(item.Request.Host+"/"+item.Target.Repository, item.Target.Tag)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+	default:
+		return nil, fmt.Errorf("%s", "unspport registry event")
+	}
+
+	return events, nil
+}
+
+func newRegistryEvent(repository string, tag string) (registryEvent, error) {
+	repo, err := name.NewRepository(repository, name.WeakValidation)
+	if err != nil {
+		return registryEvent{}, err
+	}
+
+	return registryEvent{repository: repo.Name(), tag: tag}, nil
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	revisioncontroller "github.com/openfunction/revision-controller/pkg/revision-controller"
+)
+
+const testToken = "secret-token"
+
+// fakeWatcher is a revision controller watching a repository and a tag, empty tag matches all the tags.
+type fakeWatcher struct {
+	repository string
+	tag        string
+	triggered  int
+}
+
+func (w *fakeWatcher) Start()                           {}
+func (w *fakeWatcher) Update(_ map[string]string) error { return nil }
+func (w *fakeWatcher) Stop()                            {}
+func (w *fakeWatcher) Trigger()                         { w.triggered++ }
+func (w *fakeWatcher) WatchesImage(repository, tag string) bool {
+	return repository == w.repository && (w.tag == "" || tag == "" || tag == w.tag)
+}
+
+type fakeLister struct {
+	revisionControllers []revisioncontroller.RevisionController
+}
+
+func (l *fakeLister) RevisionControllers() []revisioncontroller.RevisionController {
+	return l.revisionControllers
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestParseRegistryEvents(t *testing.T) {
+	tests := []struct {
+		fixture string
+		events  []registryEvent
+	}{
+		{
+			fixture: "harbor.json",
+			events:  []registryEvent{{repository: "harbor.example.com/library/function", tag: "v1.0.0"}},
+		},
+		{
+			fixture: "dockerhub.json",
+			events:  []registryEvent{{repository: "index.docker.io/openfunction/function", tag: "latest"}},
+		},
+		{
+			fixture: "quay.json",
+			events: []registryEvent{
+				{repository: "quay.io/openfunction/function", tag: "v1"},
+				{repository: "quay.io/openfunction/function", tag: "latest"},
+			},
+		},
+		{
+			// The pull event is ignored.
+			fixture: "distribution.json",
+			events:  []registryEvent{{repository: "registry.example.com:5000/openfunction/function", tag: "v2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			events, err := parseRegistryEvents(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Fatalf("expected %v, got %v", tt.events, events)
+			}
+		})
+	}
+}
+
+func TestParseRegistryEventsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{name: "malformed body", data: `{"push_data": `, err: true},
+		{name: "not an object", data: `["push_data"]`, err: true},
+		{name: "unknown format", data: `{"foo": "bar"}`, err: true},
+		{name: "invalid repository", data: `{"push_data": {"tag": "latest"}, "repository": {"repo_name": "Invalid Name"}}`, err: true},
+		{name: "harbor delete event", data: `{"type": "DELETE_ARTIFACT", "event_data": {"resources": [{"tag": "v1", "resource_url": "harbor.example.com/library/function:v1"}]}}`},
+		{name: "distribution event without host", data: `{"events": [{"action": "push", "target": {"repository": "function", "tag": "v1"}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := parseRegistryEvents([]byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", events)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events) != 0 {
+				t.Fatalf("expected no event, got %v", events)
+			}
+		})
+	}
+}
+
+func TestRegistryReceiver(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		method    string
+		header    string
+		query     string
+		fixture   string
+		body      string
+		status    int
+		triggered int
+	}{
+		{name: "bearer token", token: testToken, header: "Bearer " + testToken, fixture: "dockerhub.json", status: http.StatusAccepted, triggered: 1},
+		{name: "query token", token: testToken, query: "?token=" + testToken, fixture: "dockerhub.json", status: http.StatusAccepted, triggered: 1},
+		{name: "wrong token", token: testToken, header: "Bearer wrong", fixture: "dockerhub.json", status: http.StatusUnauthorized},
+		{name: "no token", token: testToken, fixture: "dockerhub.json", status: http.StatusUnauthorized},
+		{name: "no token configured", header: "Bearer ", fixture: "dockerhub.json", status: http.StatusUnauthorized},
+		{name: "unrelated repository", token: testToken, header: "Bearer " + testToken, fixture: "quay.json", status: http.StatusAccepted},
+		{name: "malformed body", token: testToken, header: "Bearer " + testToken, body: "{", status: http.StatusBadRequest},
+		{name: "wrong method", token: testToken, method: http.MethodGet, header: "Bearer " + testToken, status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watched := &fakeWatcher{repository: "index.docker.io/openfunction/function", tag: "latest"}
+			other := &fakeWatcher{repository: "index.docker.io/openfunction/other"}
+			receiver := NewRegistryReceiver("", tt.token, &fakeLister{
+				revisionControllers: []revisioncontroller.RevisionController{watched, other},
+			})
+
+			body := tt.body
+			if tt.fixture != "" {
+				body = string(readFixture(t, tt.fixture))
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			req := httptest.NewRequest(method, registryPath+tt.query, strings.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			receiver.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if watched.triggered != tt.triggered {
+				t.Fatalf("expected the watcher to be triggered %d times, got %d", tt.triggered, watched.triggered)
+			}
+
+			if other.triggered != 0 {
+				t.Fatal("expected the watcher of the other repository not to be triggered")
+			}
+		})
+	}
+}